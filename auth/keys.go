@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ScopeREST grants access to the /v1/bgg/* REST routes.
+	ScopeREST = "rest"
+	// ScopeMCP grants access to the /mcp endpoint.
+	ScopeMCP = "mcp"
+	// ScopeWrite allows the MCP tools that write to the server's BGG account, when the server
+	// enables them in http mode.
+	ScopeWrite = "write"
+)
+
+// Principal is an authenticated caller, identified either by an API key or an OAuth token.
+type Principal struct {
+	ID         string
+	Scopes     []string
+	AllowTools []string
+	DenyTools  []string
+	quota      *quota
+}
+
+// HasScope reports whether the principal was granted scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ToolAllowed reports whether the principal may call the named MCP tool.
+func (p *Principal) ToolAllowed(name string) bool {
+	for _, t := range p.DenyTools {
+		if t == name {
+			return false
+		}
+	}
+	if len(p.AllowTools) == 0 {
+		return true
+	}
+	for _, t := range p.AllowTools {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyFile is the on-disk format of the API key file. Keys are stored as SHA-256 hashes, never
+// in plain text; use HashKey (or bgg-mcp -hash-api-key) to produce them.
+//
+//	{
+//	  "keys": [
+//	    {
+//	      "id": "alice",
+//	      "hash": "sha256:9f86d0...",
+//	      "scopes": ["rest", "mcp"],
+//	      "deny_tools": ["bgg-guild-library"],
+//	      "quota": {"requests": 1000, "window": "24h"}
+//	    }
+//	  ]
+//	}
+type KeyFile struct {
+	Keys []KeyEntry `json:"keys"`
+}
+
+type KeyEntry struct {
+	ID         string      `json:"id"`
+	Hash       string      `json:"hash"`
+	Scopes     []string    `json:"scopes"`
+	AllowTools []string    `json:"allow_tools,omitempty"`
+	DenyTools  []string    `json:"deny_tools,omitempty"`
+	Quota      *QuotaEntry `json:"quota,omitempty"`
+}
+
+type QuotaEntry struct {
+	Requests int    `json:"requests"`
+	Window   string `json:"window"`
+}
+
+// KeyStore authenticates API keys against the hashes loaded from a key file.
+type KeyStore struct {
+	keys []storedKey
+}
+
+type storedKey struct {
+	hash      []byte
+	principal *Principal
+}
+
+// HashKey returns the representation of an API key stored in the key file.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// LoadKeyStore reads and validates an API key file.
+func LoadKeyStore(path string) (*KeyStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading API key file: %w", err)
+	}
+
+	var file KeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing API key file: %w", err)
+	}
+
+	store := &KeyStore{}
+	seen := map[string]bool{}
+	for i, entry := range file.Keys {
+		if entry.ID == "" {
+			return nil, fmt.Errorf("key %d: id is required", i+1)
+		}
+		if seen[entry.ID] {
+			return nil, fmt.Errorf("key %s: duplicate id", entry.ID)
+		}
+		seen[entry.ID] = true
+
+		hexHash, ok := strings.CutPrefix(entry.Hash, "sha256:")
+		if !ok {
+			return nil, fmt.Errorf("key %s: hash must start with 'sha256:'", entry.ID)
+		}
+		hash, err := hex.DecodeString(hexHash)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("key %s: invalid sha256 hash", entry.ID)
+		}
+
+		for _, scope := range entry.Scopes {
+			if scope != ScopeREST && scope != ScopeMCP && scope != ScopeWrite {
+				return nil, fmt.Errorf("key %s: unknown scope '%s' (use '%s', '%s' or '%s')", entry.ID, scope, ScopeREST, ScopeMCP, ScopeWrite)
+			}
+		}
+
+		principal := &Principal{
+			ID:         entry.ID,
+			Scopes:     entry.Scopes,
+			AllowTools: entry.AllowTools,
+			DenyTools:  entry.DenyTools,
+		}
+		if entry.Quota != nil {
+			window, err := time.ParseDuration(entry.Quota.Window)
+			if err != nil || window <= 0 || entry.Quota.Requests <= 0 {
+				return nil, fmt.Errorf("key %s: quota needs a positive request count and window such as '24h'", entry.ID)
+			}
+			principal.quota = &quota{limit: entry.Quota.Requests, window: window}
+		}
+
+		store.keys = append(store.keys, storedKey{hash: hash, principal: principal})
+	}
+
+	return store, nil
+}
+
+// Authenticate returns the principal for key, or nil when the key is unknown.
+func (s *KeyStore) Authenticate(key string) *Principal {
+	sum := sha256.Sum256([]byte(key))
+	var match *Principal
+	for _, k := range s.keys {
+		// Compare every key so the lookup time does not depend on which key matched.
+		if subtle.ConstantTimeCompare(sum[:], k.hash) == 1 {
+			match = k.principal
+		}
+	}
+	return match
+}
+
+// quota is a fixed-window request counter.
+type quota struct {
+	limit  int
+	window time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	used        int
+}
+
+// allow consumes one request and reports whether it fits in the current window, along with
+// the time the window resets.
+func (q *quota) allow(now time.Time) (bool, time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if now.Sub(q.windowStart) >= q.window {
+		q.windowStart = now
+		q.used = 0
+	}
+	reset := q.windowStart.Add(q.window)
+	if q.used >= q.limit {
+		return false, reset
+	}
+	q.used++
+	return true, reset
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeKeyFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadKeyStoreParsesHashes(t *testing.T) {
+	valid := HashKey("secret")
+	tests := []struct {
+		name    string
+		hash    string
+		scopes  string
+		wantErr string
+	}{
+		{"valid", valid, `["rest", "mcp"]`, ""},
+		{"missing prefix", strings.TrimPrefix(valid, "sha256:"), `["mcp"]`, "must start with 'sha256:'"},
+		{"not hex", "sha256:" + strings.Repeat("zz", 32), `["mcp"]`, "invalid sha256 hash"},
+		{"too short", "sha256:abcd", `["mcp"]`, "invalid sha256 hash"},
+		{"unknown scope", valid, `["admin"]`, "unknown scope 'admin'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeKeyFile(t, `{"keys": [{"id": "alice", "hash": "`+tt.hash+`", "scopes": `+tt.scopes+`}]}`)
+			store, err := LoadKeyStore(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p := store.Authenticate("secret"); p == nil || p.ID != "alice" {
+				t.Errorf("Authenticate(secret) = %+v, want alice", p)
+			}
+			if p := store.Authenticate("wrong"); p != nil {
+				t.Errorf("Authenticate(wrong) = %+v, want nil", p)
+			}
+		})
+	}
+}
+
+func TestPrincipalHasScope(t *testing.T) {
+	p := &Principal{ID: "alice", Scopes: []string{ScopeREST, ScopeWrite}}
+	tests := []struct {
+		scope string
+		want  bool
+	}{
+		{ScopeREST, true},
+		{ScopeWrite, true},
+		{ScopeMCP, false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := p.HasScope(tt.scope); got != tt.want {
+			t.Errorf("HasScope(%q) = %t, want %t", tt.scope, got, tt.want)
+		}
+	}
+}
+
+func TestPrincipalToolAllowed(t *testing.T) {
+	tests := []struct {
+		name  string
+		allow []string
+		deny  []string
+		tool  string
+		want  bool
+	}{
+		{"no lists", nil, nil, "bgg-search", true},
+		{"denied", nil, []string{"bgg-search"}, "bgg-search", false},
+		{"not denied", nil, []string{"bgg-search"}, "bgg-hot", true},
+		{"allowed", []string{"bgg-search"}, nil, "bgg-search", true},
+		{"not allowed", []string{"bgg-search"}, nil, "bgg-hot", false},
+		{"deny wins over allow", []string{"bgg-search"}, []string{"bgg-search"}, "bgg-search", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Principal{ID: "alice", AllowTools: tt.allow, DenyTools: tt.deny}
+			if got := p.ToolAllowed(tt.tool); got != tt.want {
+				t.Errorf("ToolAllowed(%q) = %t, want %t", tt.tool, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuotaResetsEachWindow(t *testing.T) {
+	q := &quota{limit: 2, window: time.Hour}
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		at        time.Duration
+		want      bool
+		wantReset time.Duration
+	}{
+		{0, true, time.Hour},
+		{time.Minute, true, time.Hour},
+		{59 * time.Minute, false, time.Hour},
+		{time.Hour, true, 2 * time.Hour},
+		{time.Hour + time.Second, true, 2 * time.Hour},
+		{time.Hour + 2*time.Second, false, 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		ok, reset := q.allow(start.Add(tt.at))
+		if ok != tt.want || !reset.Equal(start.Add(tt.wantReset)) {
+			t.Errorf("allow at +%s = %t, reset %s; want %t, reset %s", tt.at, ok, reset, tt.want, start.Add(tt.wantReset))
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated principal.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the authenticated principal, or nil when the request was not
+// authenticated (stdio mode or authentication disabled).
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Authenticator checks API keys and, when configured, OAuth bearer tokens.
+type Authenticator struct {
+	Keys  *KeyStore
+	OAuth *OAuthVerifier
+}
+
+// Enabled reports whether any authentication method is configured.
+func (a *Authenticator) Enabled() bool {
+	return a != nil && (a.Keys != nil || a.OAuth != nil)
+}
+
+// Require wraps next so that only callers holding scope can reach it. API keys are accepted
+// as "Authorization: Bearer <key>" or "X-API-Key: <key>". When authentication is disabled the
+// handler is returned unchanged.
+func (a *Authenticator) Require(scope string, next http.Handler) http.Handler {
+	if !a.Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Let CORS preflight requests through; they never carry credentials.
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := bearerToken(r)
+		if token == "" {
+			a.unauthorized(w, "missing credentials")
+			return
+		}
+
+		var principal *Principal
+		if a.Keys != nil {
+			principal = a.Keys.Authenticate(token)
+		}
+		if principal == nil && a.OAuth != nil {
+			p, err := a.OAuth.Verify(r.Context(), token)
+			if err != nil && err != errInvalidToken {
+				writeError(w, http.StatusServiceUnavailable, err.Error())
+				return
+			}
+			principal = p
+		}
+		if principal == nil {
+			a.unauthorized(w, "invalid credentials")
+			return
+		}
+
+		if !principal.HasScope(scope) {
+			if a.OAuth != nil {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="bgg:%s", resource_metadata="%s"`, scope, a.OAuth.MetadataURL()))
+			}
+			writeError(w, http.StatusForbidden, fmt.Sprintf("key '%s' is not allowed to access %s", principal.ID, scope))
+			return
+		}
+
+		if principal.quota != nil {
+			ok, reset := principal.quota.allow(time.Now())
+			if !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(reset).Seconds())+1))
+				writeError(w, http.StatusTooManyRequests, "request quota exceeded")
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+func (a *Authenticator) unauthorized(w http.ResponseWriter, message string) {
+	challenge := `Bearer realm="bgg-mcp"`
+	if a.OAuth != nil {
+		challenge = fmt.Sprintf(`Bearer realm="bgg-mcp", resource_metadata="%s"`, a.OAuth.MetadataURL())
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	writeError(w, http.StatusUnauthorized, message)
+}
+
+func bearerToken(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		if scheme, token, ok := strings.Cut(h, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// ToolMiddleware rejects MCP tool calls the authenticated principal is not allowed to make.
+func ToolMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if p := PrincipalFromContext(ctx); p != nil && !p.ToolAllowed(request.Params.Name) {
+			return mcp.NewToolResultError(fmt.Sprintf("Tool '%s' is not permitted for this API key", request.Params.Name)), nil
+		}
+		return next(ctx, request)
+	}
+}
+
+// ToolFilter hides the tools the authenticated principal is not allowed to call.
+func ToolFilter(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	p := PrincipalFromContext(ctx)
+	if p == nil {
+		return tools
+	}
+	allowed := make([]mcp.Tool, 0, len(tools))
+	for _, t := range tools {
+		if p.ToolAllowed(t.Name) {
+			allowed = append(allowed, t)
+		}
+	}
+	return allowed
+}
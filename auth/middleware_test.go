@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func hashBytes(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+func TestRequireChecksKeyScopeAndQuota(t *testing.T) {
+	store := &KeyStore{}
+	for _, p := range []*Principal{
+		{ID: "reader", Scopes: []string{ScopeREST}},
+		{ID: "agent", Scopes: []string{ScopeMCP}},
+		{ID: "limited", Scopes: []string{ScopeREST}, quota: &quota{limit: 1, window: time.Hour}},
+	} {
+		store.keys = append(store.keys, storedKey{hash: hashBytes(p.ID + "-key"), principal: p})
+	}
+	a := &Authenticator{Keys: store}
+	handler := a.Require(ScopeREST, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p := PrincipalFromContext(r.Context()); p == nil {
+			t.Error("handler reached without a principal")
+		}
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"no credentials", "", "", http.StatusUnauthorized},
+		{"unknown key", "Authorization", "Bearer nope", http.StatusUnauthorized},
+		{"bearer key", "Authorization", "Bearer reader-key", http.StatusOK},
+		{"X-API-Key header", "X-API-Key", "reader-key", http.StatusOK},
+		{"missing scope", "Authorization", "Bearer agent-key", http.StatusForbidden},
+		{"within quota", "X-API-Key", "limited-key", http.StatusOK},
+		{"over quota", "X-API-Key", "limited-key", http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/bgg/hot", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolFilterAndMiddlewareApplyToolLists(t *testing.T) {
+	tools := []mcp.Tool{{Name: "bgg-search"}, {Name: "bgg-hot"}, {Name: "bgg-log-play"}}
+	tests := []struct {
+		name      string
+		principal *Principal
+		want      []string
+	}{
+		{"unauthenticated", nil, []string{"bgg-search", "bgg-hot", "bgg-log-play"}},
+		{"deny list", &Principal{ID: "a", DenyTools: []string{"bgg-log-play"}}, []string{"bgg-search", "bgg-hot"}},
+		{"allow list", &Principal{ID: "a", AllowTools: []string{"bgg-hot"}}, []string{"bgg-hot"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = WithPrincipal(ctx, tt.principal)
+			}
+
+			var got []string
+			for _, tool := range ToolFilter(ctx, tools) {
+				got = append(got, tool.Name)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ToolFilter = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("ToolFilter = %v, want %v", got, tt.want)
+				}
+			}
+
+			handler := ToolMiddleware(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				return mcp.NewToolResultText("ok"), nil
+			})
+			for _, tool := range tools {
+				var request mcp.CallToolRequest
+				request.Params.Name = tool.Name
+				result, err := handler(ctx, request)
+				if err != nil {
+					t.Fatal(err)
+				}
+				allowed := tt.principal == nil || tt.principal.ToolAllowed(tool.Name)
+				if result.IsError == allowed {
+					t.Errorf("calling %s: IsError = %t, want %t", tool.Name, result.IsError, !allowed)
+				}
+			}
+		})
+	}
+}
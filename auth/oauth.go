@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAuthConfig configures OAuth 2.1 protected-resource mode as described by the MCP
+// authorization spec: the server advertises its authorization server through RFC 9728
+// metadata and validates bearer tokens with RFC 7662 token introspection.
+type OAuthConfig struct {
+	// Resource is the canonical URI of this server's MCP endpoint; tokens must list it as audience.
+	Resource             string
+	AuthorizationServers []string
+	IntrospectionURL     string
+	ClientID             string
+	ClientSecret         string
+}
+
+// OAuth scopes map onto the API key scopes.
+const (
+	OAuthScopeREST  = "bgg:rest"
+	OAuthScopeMCP   = "bgg:mcp"
+	OAuthScopeWrite = "bgg:write"
+)
+
+// introspectionCacheTTL bounds how long a positive introspection result is reused.
+const introspectionCacheTTL = time.Minute
+
+// introspectionCacheMax bounds the number of cached introspection results.
+const introspectionCacheMax = 10000
+
+var errInvalidToken = errors.New("invalid or expired token")
+
+// OAuthVerifier validates bearer tokens issued by an external authorization server.
+type OAuthVerifier struct {
+	cfg    OAuthConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cachedIntrospection
+}
+
+type cachedIntrospection struct {
+	principal *Principal
+	expires   time.Time
+}
+
+type introspectionResponse struct {
+	Active   bool            `json:"active"`
+	Scope    string          `json:"scope"`
+	Subject  string          `json:"sub"`
+	ClientID string          `json:"client_id"`
+	Audience json.RawMessage `json:"aud"`
+	Expiry   int64           `json:"exp"`
+}
+
+func NewOAuthVerifier(cfg OAuthConfig) (*OAuthVerifier, error) {
+	if cfg.Resource == "" {
+		return nil, fmt.Errorf("oauth: resource URI is required")
+	}
+	if len(cfg.AuthorizationServers) == 0 {
+		return nil, fmt.Errorf("oauth: at least one authorization server is required")
+	}
+	if cfg.IntrospectionURL == "" {
+		return nil, fmt.Errorf("oauth: introspection URL is required")
+	}
+	return &OAuthVerifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		cache:  map[string]cachedIntrospection{},
+	}, nil
+}
+
+// MetadataURL returns the URL of the protected resource metadata document for resource.
+func (v *OAuthVerifier) MetadataURL() string {
+	u, err := url.Parse(v.cfg.Resource)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host + "/.well-known/oauth-protected-resource"
+}
+
+// MetadataHandler serves the RFC 9728 protected resource metadata document.
+func (v *OAuthVerifier) MetadataHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"resource":                 v.cfg.Resource,
+			"authorization_servers":    v.cfg.AuthorizationServers,
+			"bearer_methods_supported": []string{"header"},
+			"scopes_supported":         []string{OAuthScopeMCP, OAuthScopeREST, OAuthScopeWrite},
+		})
+	})
+}
+
+// Verify introspects token and returns its principal.
+func (v *OAuthVerifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	sum := sha256.Sum256([]byte(token))
+	cacheKey := hex.EncodeToString(sum[:])
+
+	v.mu.Lock()
+	if cached, ok := v.cache[cacheKey]; ok {
+		if time.Now().Before(cached.expires) {
+			v.mu.Unlock()
+			return cached.principal, nil
+		}
+		delete(v.cache, cacheKey)
+	}
+	v.mu.Unlock()
+
+	form := url.Values{}
+	form.Set("token", token)
+	form.Set("token_type_hint", "access_token")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.cfg.IntrospectionURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if v.cfg.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(v.cfg.ClientID), url.QueryEscape(v.cfg.ClientSecret))
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oauth: introspection request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oauth: introspection returned status %d", resp.StatusCode)
+	}
+
+	var ir introspectionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ir); err != nil {
+		return nil, fmt.Errorf("oauth: invalid introspection response: %w", err)
+	}
+	if !ir.Active {
+		return nil, errInvalidToken
+	}
+	if !audienceContains(ir.Audience, v.cfg.Resource) {
+		return nil, errInvalidToken
+	}
+
+	principal := &Principal{ID: ir.Subject}
+	if principal.ID == "" {
+		principal.ID = ir.ClientID
+	}
+	for _, scope := range strings.Fields(ir.Scope) {
+		switch scope {
+		case OAuthScopeMCP:
+			principal.Scopes = append(principal.Scopes, ScopeMCP)
+		case OAuthScopeREST:
+			principal.Scopes = append(principal.Scopes, ScopeREST)
+		case OAuthScopeWrite:
+			principal.Scopes = append(principal.Scopes, ScopeWrite)
+		}
+	}
+
+	expires := time.Now().Add(introspectionCacheTTL)
+	if ir.Expiry > 0 && time.Unix(ir.Expiry, 0).Before(expires) {
+		expires = time.Unix(ir.Expiry, 0)
+	}
+	v.store(cacheKey, cachedIntrospection{principal: principal, expires: expires})
+
+	return principal, nil
+}
+
+// store caches an introspection result. Expired entries are dropped first; if the cache is
+// still full, the entry closest to expiry makes room.
+func (v *OAuthVerifier) store(key string, entry cachedIntrospection) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if len(v.cache) >= introspectionCacheMax {
+		now := time.Now()
+		for k, cached := range v.cache {
+			if !now.Before(cached.expires) {
+				delete(v.cache, k)
+			}
+		}
+	}
+	if len(v.cache) >= introspectionCacheMax {
+		var oldest string
+		for k, cached := range v.cache {
+			if oldest == "" || cached.expires.Before(v.cache[oldest].expires) {
+				oldest = k
+			}
+		}
+		delete(v.cache, oldest)
+	}
+	v.cache[key] = entry
+}
+
+// audienceContains reports whether the introspected "aud" claim, a string or an array of
+// strings, includes resource.
+func audienceContains(raw json.RawMessage, resource string) bool {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single == resource
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		for _, aud := range list {
+			if aud == resource {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOAuthVerifierCachesIntrospection(t *testing.T) {
+	const resource = "https://bgg.example.com/mcp"
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		switch r.PostForm.Get("token") {
+		case "good":
+			fmt.Fprintf(w, `{"active": true, "sub": "alice", "scope": "bgg:mcp bgg:write other", "aud": [%q]}`, resource)
+		case "expiring":
+			fmt.Fprintf(w, `{"active": true, "sub": "bob", "scope": "bgg:rest", "aud": %q, "exp": %d}`, resource, time.Now().Add(-time.Second).Unix())
+		case "elsewhere":
+			fmt.Fprint(w, `{"active": true, "sub": "carol", "scope": "bgg:mcp", "aud": "https://other.example.com"}`)
+		default:
+			fmt.Fprint(w, `{"active": false}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	v, err := NewOAuthVerifier(OAuthConfig{Resource: resource, AuthorizationServers: []string{"https://auth.example.com"}, IntrospectionURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		wantID    string
+		wantErr   error
+		wantCalls int32
+	}{
+		{"introspected", "good", "alice", nil, 1},
+		{"cached", "good", "alice", nil, 0},
+		{"already expired is not reused", "expiring", "bob", nil, 1},
+		{"expired entry introspected again", "expiring", "bob", nil, 1},
+		{"inactive", "revoked", "", errInvalidToken, 1},
+		{"inactive is not cached", "revoked", "", errInvalidToken, 1},
+		{"wrong audience", "elsewhere", "", errInvalidToken, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := calls.Load()
+			p, err := v.Verify(context.Background(), tt.token)
+			if err != tt.wantErr {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && p.ID != tt.wantID {
+				t.Errorf("principal = %q, want %q", p.ID, tt.wantID)
+			}
+			if got := calls.Load() - before; got != tt.wantCalls {
+				t.Errorf("made %d introspection requests, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+
+	p, _ := v.Verify(context.Background(), "good")
+	if !p.HasScope(ScopeMCP) || !p.HasScope(ScopeWrite) || p.HasScope(ScopeREST) {
+		t.Errorf("scopes = %v, want mcp and write", p.Scopes)
+	}
+}
@@ -0,0 +1,417 @@
+// Package config loads the server configuration from an optional YAML file, with environment
+// variables overriding file values.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the server configuration. Every field is optional in the file.
+type Config struct {
+	Server   ServerConfig    `yaml:"server"`
+	BGG      BGGConfig       `yaml:"bgg"`
+	Defaults DefaultsConfig  `yaml:"defaults"`
+	Limits   LimitsConfig    `yaml:"limits"`
+	Upstream UpstreamConfig  `yaml:"upstream"`
+	Prices   PricesConfig    `yaml:"prices"`
+	FX       FXConfig        `yaml:"fx"`
+	Tools    map[string]bool `yaml:"tools"`
+	Prompts  map[string]bool `yaml:"prompts"`
+}
+
+type ServerConfig struct {
+	Mode        string `yaml:"mode"`
+	Port        string `yaml:"port"`
+	BaseURL     string `yaml:"base_url"`
+	APIKeysFile string `yaml:"api_keys_file"`
+	// AllowWrites registers the tools that write to the BGG account in http mode. They are
+	// always available in stdio mode.
+	AllowWrites bool `yaml:"allow_writes"`
+	// PublicMetrics serves /metrics without credentials when authentication is enabled.
+	PublicMetrics bool `yaml:"public_metrics"`
+}
+
+type BGGConfig struct {
+	Username     string `yaml:"username"`
+	TokenFile    string `yaml:"token_file"`
+	PasswordFile string `yaml:"password_file"`
+	// OfficialAccounts and OfficialAccountsFile list designer and publisher accounts flagged as
+	// official in threads, on top of those matched from a game's credits.
+	OfficialAccounts     []string `yaml:"official_accounts"`
+	OfficialAccountsFile string   `yaml:"official_accounts_file"`
+}
+
+type DefaultsConfig struct {
+	Currency    string `yaml:"currency"`
+	Destination string `yaml:"destination"`
+}
+
+type LimitsConfig struct {
+	SearchResults          int `yaml:"search_results"`
+	RulesMaxPages          int `yaml:"rules_max_pages"`
+	ForumSearchMaxPages    int `yaml:"forum_search_max_pages"`
+	RecommendationMinVotes int `yaml:"recommendation_min_votes"`
+	LudographyResults      int `yaml:"ludography_results"`
+}
+
+type UpstreamConfig struct {
+	Timeout         time.Duration `yaml:"timeout"`
+	CacheTTL        time.Duration `yaml:"cache_ttl"`
+	CacheMaxEntries int           `yaml:"cache_max_entries"`
+}
+
+// PricesConfig lists the price providers bgg-price merges. With none listed, prices come from
+// boardgameprices.co.uk alone; list it explicitly to keep it alongside other providers. The
+// providers can only be set in the file; no environment variable overrides them.
+type PricesConfig struct {
+	Providers []PriceProviderConfig `yaml:"providers"`
+}
+
+// PriceProviderConfig is one price provider. Type is boardgameprices, feed (a JSON or CSV
+// document at URL) or file (a JSON or CSV file at Path).
+type PriceProviderConfig struct {
+	Name     string        `yaml:"name"`
+	Type     string        `yaml:"type"`
+	URL      string        `yaml:"url"`
+	Path     string        `yaml:"path"`
+	Format   string        `yaml:"format"`
+	Currency string        `yaml:"currency"`
+	Country  string        `yaml:"country"`
+	ShipsTo  []string      `yaml:"ships_to"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// FXConfig sets where exchange rates for price conversion come from. Without either source
+// a built-in table of approximate rates is used.
+type FXConfig struct {
+	RatesFile   string        `yaml:"rates_file"`
+	ProviderURL string        `yaml:"provider_url"`
+	Refresh     time.Duration `yaml:"refresh"`
+}
+
+// Default returns the configuration used when no file or environment overrides are given.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{Mode: "stdio", Port: "8080"},
+		Defaults: DefaultsConfig{
+			Currency:    "USD",
+			Destination: "US",
+		},
+		Limits: LimitsConfig{
+			SearchResults:          30,
+			RulesMaxPages:          10,
+			ForumSearchMaxPages:    3,
+			RecommendationMinVotes: 30,
+			LudographyResults:      100,
+		},
+		Upstream: UpstreamConfig{
+			Timeout:         30 * time.Second,
+			CacheTTL:        10 * time.Minute,
+			CacheMaxEntries: 1000,
+		},
+		Tools:   map[string]bool{},
+		Prompts: map[string]bool{},
+	}
+}
+
+// Load builds the configuration from the defaults, the YAML file at path (skipped when path is
+// empty), the overrides callback (used for command-line flags) and finally the environment. All
+// validation problems are reported together.
+func Load(path string, overrides func(*Config)) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading config file: %w", err)
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+		if cfg.Tools == nil {
+			cfg.Tools = map[string]bool{}
+		}
+		if cfg.Prompts == nil {
+			cfg.Prompts = map[string]bool{}
+		}
+	}
+
+	if overrides != nil {
+		overrides(cfg)
+	}
+
+	errs := cfg.applyEnv()
+	errs = append(errs, cfg.validate()...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n  %s", strings.Join(errorStrings(errs), "\n  "))
+	}
+	return cfg, nil
+}
+
+// ToolEnabled reports whether the named tool should be registered. Tools are enabled unless
+// the configuration disables them.
+func (c *Config) ToolEnabled(name string) bool {
+	enabled, ok := c.Tools[name]
+	return !ok || enabled
+}
+
+// PromptEnabled reports whether the named prompt should be registered.
+func (c *Config) PromptEnabled(name string) bool {
+	enabled, ok := c.Prompts[name]
+	return !ok || enabled
+}
+
+// CheckNames reports tools and prompts named in the configuration that the server does not
+// provide, which are most likely typos.
+func (c *Config) CheckNames(tools, prompts []string) error {
+	var errs []error
+	errs = append(errs, unknownNames("tools", c.Tools, tools)...)
+	errs = append(errs, unknownNames("prompts", c.Prompts, prompts)...)
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n  %s", strings.Join(errorStrings(errs), "\n  "))
+	}
+	return nil
+}
+
+func unknownNames(section string, configured map[string]bool, known []string) []error {
+	valid := map[string]bool{}
+	for _, name := range known {
+		valid[name] = true
+	}
+	var names []string
+	for name := range configured {
+		if !valid[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		errs = append(errs, fmt.Errorf("%s: unknown name %q (available: %s)", section, name, strings.Join(known, ", ")))
+	}
+	return errs
+}
+
+// applyEnv overrides file values with the environment variables that are set.
+func (c *Config) applyEnv() []error {
+	var errs []error
+
+	setString := func(env string, target *string) {
+		if v := os.Getenv(env); v != "" {
+			*target = v
+		}
+	}
+	setInt := func(env string, target *int) {
+		if v := os.Getenv(env); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a whole number", env, v))
+				return
+			}
+			*target = n
+		}
+	}
+	setDuration := func(env string, target *time.Duration) {
+		if v := os.Getenv(env); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a duration (e.g. 30s, 10m)", env, v))
+				return
+			}
+			*target = d
+		}
+	}
+	setBool := func(env string, target *bool) {
+		if v := os.Getenv(env); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not true or false", env, v))
+				return
+			}
+			*target = b
+		}
+	}
+	setList := func(env string, target *[]string) {
+		if v := os.Getenv(env); v != "" {
+			*target = strings.Split(v, ",")
+		}
+	}
+	setDisabled := func(env string, target map[string]bool) {
+		for _, name := range strings.Split(os.Getenv(env), ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				target[name] = false
+			}
+		}
+	}
+
+	setString("MCP_MODE", &c.Server.Mode)
+	setString("MCP_PORT", &c.Server.Port)
+	setString("MCP_BASE_URL", &c.Server.BaseURL)
+	setString("MCP_API_KEYS_FILE", &c.Server.APIKeysFile)
+	setBool("MCP_ALLOW_WRITES", &c.Server.AllowWrites)
+	setBool("MCP_PUBLIC_METRICS", &c.Server.PublicMetrics)
+	setString("BGG_USERNAME", &c.BGG.Username)
+	setString("BGG_API_TOKEN_FILE", &c.BGG.TokenFile)
+	setString("BGG_PASSWORD_FILE", &c.BGG.PasswordFile)
+	setList("BGG_OFFICIAL_ACCOUNTS", &c.BGG.OfficialAccounts)
+	setString("BGG_OFFICIAL_ACCOUNTS_FILE", &c.BGG.OfficialAccountsFile)
+	setString("BGG_DEFAULT_CURRENCY", &c.Defaults.Currency)
+	setString("BGG_DEFAULT_DESTINATION", &c.Defaults.Destination)
+	setInt("BGG_SEARCH_LIMIT", &c.Limits.SearchResults)
+	setInt("BGG_RULES_MAX_PAGES", &c.Limits.RulesMaxPages)
+	setInt("BGG_FORUM_SEARCH_MAX_PAGES", &c.Limits.ForumSearchMaxPages)
+	setInt("BGG_RECOMMENDATION_MIN_VOTES", &c.Limits.RecommendationMinVotes)
+	setInt("BGG_LUDOGRAPHY_LIMIT", &c.Limits.LudographyResults)
+	setString("BGG_FX_RATES_FILE", &c.FX.RatesFile)
+	setString("BGG_FX_PROVIDER_URL", &c.FX.ProviderURL)
+	setDuration("BGG_FX_REFRESH", &c.FX.Refresh)
+	setDuration("BGG_UPSTREAM_TIMEOUT", &c.Upstream.Timeout)
+	setDuration("BGG_CACHE_TTL", &c.Upstream.CacheTTL)
+	setInt("BGG_CACHE_MAX_ENTRIES", &c.Upstream.CacheMaxEntries)
+	setDisabled("MCP_DISABLED_TOOLS", c.Tools)
+	setDisabled("MCP_DISABLED_PROMPTS", c.Prompts)
+
+	return errs
+}
+
+var (
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+	countryPattern  = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+func (c *Config) validate() []error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Server.Mode != "stdio" && c.Server.Mode != "http" {
+		fail("server.mode: %q must be stdio or http", c.Server.Mode)
+	}
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		fail("server.port: %q is not a valid port", c.Server.Port)
+	}
+	if c.Server.BaseURL != "" {
+		if u, err := url.Parse(c.Server.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("server.base_url: %q must be an absolute http or https URL", c.Server.BaseURL)
+		}
+	}
+
+	c.Defaults.Currency = strings.ToUpper(c.Defaults.Currency)
+	c.Defaults.Destination = strings.ToUpper(c.Defaults.Destination)
+	if !currencyPattern.MatchString(c.Defaults.Currency) {
+		fail("defaults.currency: %q must be a three-letter currency code", c.Defaults.Currency)
+	}
+	if !countryPattern.MatchString(c.Defaults.Destination) {
+		fail("defaults.destination: %q must be a two-letter country code", c.Defaults.Destination)
+	}
+
+	positive := []struct {
+		name  string
+		value int
+	}{
+		{"limits.search_results", c.Limits.SearchResults},
+		{"limits.rules_max_pages", c.Limits.RulesMaxPages},
+		{"limits.forum_search_max_pages", c.Limits.ForumSearchMaxPages},
+		{"limits.recommendation_min_votes", c.Limits.RecommendationMinVotes},
+		{"limits.ludography_results", c.Limits.LudographyResults},
+	}
+	for _, p := range positive {
+		if p.value < 1 {
+			fail("%s: %d must be at least 1", p.name, p.value)
+		}
+	}
+
+	if c.Upstream.Timeout < 0 {
+		fail("upstream.timeout: %s must not be negative", c.Upstream.Timeout)
+	}
+	if c.Upstream.CacheTTL < 0 {
+		fail("upstream.cache_ttl: %s must not be negative (use 0 to disable the cache)", c.Upstream.CacheTTL)
+	}
+	if c.Upstream.CacheMaxEntries < 1 {
+		fail("upstream.cache_max_entries: %d must be at least 1", c.Upstream.CacheMaxEntries)
+	}
+
+	if c.FX.ProviderURL != "" {
+		if u, err := url.Parse(c.FX.ProviderURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("fx.provider_url: %q must be an absolute http or https URL", c.FX.ProviderURL)
+		}
+	}
+	if c.FX.Refresh < 0 {
+		fail("fx.refresh: %s must not be negative", c.FX.Refresh)
+	}
+
+	names := map[string]bool{}
+	for i := range c.Prices.Providers {
+		p := &c.Prices.Providers[i]
+		field := fmt.Sprintf("prices.providers[%d]", i)
+		if p.Name == "" {
+			p.Name = p.Type
+		}
+		if names[p.Name] {
+			fail("%s.name: %q is used by another provider", field, p.Name)
+		}
+		names[p.Name] = true
+
+		switch p.Type {
+		case "boardgameprices":
+		case "feed":
+			if u, err := url.Parse(p.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				fail("%s.url: %q must be an absolute http or https URL", field, p.URL)
+			}
+		case "file":
+			if p.Path == "" {
+				fail("%s.path: required for file providers", field)
+			}
+		default:
+			fail("%s.type: %q must be boardgameprices, feed or file", field, p.Type)
+		}
+		if p.Type == "feed" || p.Type == "file" {
+			p.Currency = strings.ToUpper(p.Currency)
+			if !currencyPattern.MatchString(p.Currency) {
+				fail("%s.currency: %q must be a three-letter currency code", field, p.Currency)
+			}
+		}
+		if p.Format != "" && p.Format != "json" && p.Format != "csv" {
+			fail("%s.format: %q must be json or csv", field, p.Format)
+		}
+		p.Country = strings.ToUpper(p.Country)
+		if p.Country != "" && !countryPattern.MatchString(p.Country) {
+			fail("%s.country: %q must be a two-letter country code", field, p.Country)
+		}
+		for j, dest := range p.ShipsTo {
+			p.ShipsTo[j] = strings.ToUpper(dest)
+			if !countryPattern.MatchString(p.ShipsTo[j]) {
+				fail("%s.ships_to: %q must be a two-letter country code", field, dest)
+			}
+		}
+		if p.Timeout < 0 {
+			fail("%s.timeout: %s must not be negative", field, p.Timeout)
+		}
+	}
+
+	return errs
+}
+
+func errorStrings(errs []error) []string {
+	out := make([]string, len(errs))
+	for i, err := range errs {
+		out[i] = err.Error()
+	}
+	return out
+}
@@ -5,6 +5,7 @@ go 1.23
 require (
 	github.com/kkjdaniel/gogeek v1.5.1
 	github.com/mark3labs/mcp-go v0.39.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -19,5 +20,4 @@ require (
 	github.com/wk8/go-ordered-map/v2 v2.1.8 // indirect
 	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
 	go.uber.org/ratelimit v0.3.1 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
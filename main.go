@@ -8,15 +8,24 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/kkjdanie/bgg-mcp/auth"
+	"github.com/kkjdanie/bgg-mcp/config"
+	"github.com/kkjdanie/bgg-mcp/metrics"
 	"github.com/kkjdanie/bgg-mcp/prompts"
 	"github.com/kkjdanie/bgg-mcp/tools"
+	"github.com/kkjdanie/bgg-mcp/tracing"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
-func createMCPServer() *server.MCPServer {
+func createMCPServer(cfg *config.Config) (*server.MCPServer, error) {
+	hooks := &server.Hooks{}
+	tools.RegisterIdentityHooks(hooks)
+
 	s := server.NewMCPServer(
 		"BGG MCP",
 		"1.4.0",
@@ -24,99 +33,365 @@ func createMCPServer() *server.MCPServer {
 		server.WithPromptCapabilities(true),
 		server.WithLogging(),
 		server.WithRecovery(),
+		server.WithToolHandlerMiddleware(tracing.ToolMiddleware),
+		server.WithToolHandlerMiddleware(metrics.ToolMiddleware),
+		server.WithToolHandlerMiddleware(auth.ToolMiddleware),
+		server.WithToolHandlerMiddleware(tools.BGGAuthToolMiddleware),
+		server.WithToolFilter(auth.ToolFilter),
+		server.WithHooks(hooks),
 	)
 
-	detailsTool, detailsHandler := tools.DetailsTool()
-	s.AddTool(detailsTool, detailsHandler)
-
-	collectionTool, collectionHandler := tools.CollectionTool()
-	s.AddTool(collectionTool, collectionHandler)
-
-	hotnessTool, hotnessHandler := tools.HotnessTool()
-	s.AddTool(hotnessTool, hotnessHandler)
-
-	userTool, userHandler := tools.UserTool()
-	s.AddTool(userTool, userHandler)
-
-	searchTool, searchHandler := tools.SearchTool()
-	s.AddTool(searchTool, searchHandler)
-
-	priceTool, priceHandler := tools.PriceTool()
-	s.AddTool(priceTool, priceHandler)
-
-	tradeFinderTool, tradeFinderHandler := tools.TradeFinderTool()
-	s.AddTool(tradeFinderTool, tradeFinderHandler)
-
-	recommenderTool, recommenderHandler := tools.RecommenderTool()
-	s.AddTool(recommenderTool, recommenderHandler)
+	// Every tool and prompt is listed so the names in the config file can be checked, but only
+	// the enabled ones are registered.
+	var toolNames, promptNames []string
+	addTool := func(tool mcp.Tool, handler server.ToolHandlerFunc) {
+		toolNames = append(toolNames, tool.Name)
+		if cfg.ToolEnabled(tool.Name) {
+			s.AddTool(tool, handler)
+		}
+	}
+	// The write tools act on the server's own BGG account, so http mode only offers them when
+	// writes are explicitly allowed.
+	addWriteTool := func(tool mcp.Tool, handler server.ToolHandlerFunc) {
+		if cfg.Server.Mode == "http" && !cfg.Server.AllowWrites {
+			toolNames = append(toolNames, tool.Name)
+			return
+		}
+		addTool(tool, handler)
+	}
+	addPrompt := func(prompt mcp.Prompt, handler server.PromptHandlerFunc) {
+		promptNames = append(promptNames, prompt.Name)
+		if cfg.PromptEnabled(prompt.Name) {
+			s.AddPrompt(prompt, handler)
+		}
+	}
 
-	rulesTool, rulesHandler := tools.RulesTool()
-	s.AddTool(rulesTool, rulesHandler)
+	addTool(tools.DetailsTool())
+	addTool(tools.CollectionTool())
+	addTool(tools.HotnessTool())
+	addTool(tools.UserTool())
+	addTool(tools.SearchTool())
+	addTool(tools.PriceTool())
+	addTool(tools.MarketplaceTool())
+	addTool(tools.CollectionValueTool())
+	addTool(tools.BudgetPlannerTool())
+	addTool(tools.SaleListTool())
+	addTool(tools.TradeFinderTool())
+	addTool(tools.RecommenderTool())
+	addTool(tools.RulesTool())
+	addTool(tools.ThreadDetailsTool())
+	addTool(tools.RulesAnswerTool())
+	addTool(tools.ForumSearchTool())
+	addTool(tools.CommentsTool())
+	addTool(tools.DesignerTool())
+	addTool(tools.PublisherTool())
+	addTool(tools.FamilyTool())
+	addTool(tools.GeekListTool())
+	addTool(tools.GuildTool())
+	addTool(tools.GuildLibraryTool())
+	addWriteTool(tools.LogPlayTool())
+	addWriteTool(tools.CollectionUpdateTool())
+	addWriteTool(tools.CollectionRemoveTool())
 
-	threadDetailsTool, threadDetailsHandler := tools.ThreadDetailsTool()
-	s.AddTool(threadDetailsTool, threadDetailsHandler)
+	addPrompt(prompts.TradeSalesPrompt())
+	addPrompt(prompts.GameRecommendationsPrompt())
 
-	prompts.RegisterPrompts(s)
+	if err := cfg.CheckNames(toolNames, promptNames); err != nil {
+		return nil, err
+	}
 
-	return s
+	return s, nil
 }
 
 func main() {
+	var configFile string
 	var mode string
 	var port string
+	var bggToken string
+	var bggTokenFile string
+	var apiKeysFile string
+	var hashAPIKey string
 	
+	flag.StringVar(&configFile, "config", "", "YAML configuration file (overrides MCP_CONFIG_FILE)")
 	flag.StringVar(&mode, "mode", "stdio", "Server mode: stdio or http")
 	flag.StringVar(&port, "port", "8080", "Port for HTTP server (only used in http mode)")
+	flag.StringVar(&bggToken, "bgg-token", "", "BoardGameGeek XML API application token (overrides BGG_API_TOKEN)")
+	flag.StringVar(&bggTokenFile, "bgg-token-file", "", "File containing the BoardGameGeek XML API application token")
+	flag.StringVar(&apiKeysFile, "api-keys-file", "", "API key file enabling authentication in http mode (overrides MCP_API_KEYS_FILE)")
+	flag.StringVar(&hashAPIKey, "hash-api-key", "", "Print the hash of an API key for use in the API key file, then exit")
 	flag.Parse()
 
-	if envMode := os.Getenv("MCP_MODE"); envMode != "" {
-		mode = envMode
+	if hashAPIKey != "" {
+		fmt.Println(auth.HashKey(hashAPIKey))
+		return
 	}
-	
-	if envPort := os.Getenv("MCP_PORT"); envPort != "" {
-		port = envPort
+
+	if configFile == "" {
+		configFile = os.Getenv("MCP_CONFIG_FILE")
+	}
+
+	// Flags given on the command line override the config file; environment variables override
+	// both, except where a flag is documented to override its env var.
+	cfg, err := config.Load(configFile, func(cfg *config.Config) {
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "mode":
+				cfg.Server.Mode = mode
+			case "port":
+				cfg.Server.Port = port
+			}
+		})
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if bggTokenFile != "" {
+		cfg.BGG.TokenFile = bggTokenFile
+	}
+	if apiKeysFile != "" {
+		cfg.Server.APIKeysFile = apiKeysFile
 	}
 
-	mcpServer := createMCPServer()
+	token, err := resolveBGGToken(bggToken, cfg.BGG.TokenFile)
+	if err != nil {
+		log.Fatalf("Invalid BGG token configuration: %v", err)
+	}
+	if token == "" {
+		log.Println("Warning: no BGG API token configured; BGG XML API requests may be rejected. Set BGG_API_TOKEN or use -bgg-token.")
+	}
+	tools.ConfigureUpstream(tools.UpstreamConfig{
+		BGGToken:        token,
+		Timeout:         cfg.Upstream.Timeout,
+		CacheTTL:        cfg.Upstream.CacheTTL,
+		CacheMaxEntries: cfg.Upstream.CacheMaxEntries,
+	})
+	password, err := resolveBGGPassword(cfg.BGG.PasswordFile)
+	if err != nil {
+		log.Fatalf("Invalid BGG login configuration: %v", err)
+	}
+	tools.ConfigureAccount(tools.AccountConfig{
+		Username:     cfg.BGG.Username,
+		Password:     password,
+		RequireScope: cfg.Server.Mode == "http",
+	})
+	if err := tools.ConfigureOfficialAccounts(tools.OfficialAccountsConfig{
+		Accounts: cfg.BGG.OfficialAccounts,
+		File:     cfg.BGG.OfficialAccountsFile,
+	}); err != nil {
+		log.Fatalf("Invalid official accounts configuration: %v", err)
+	}
+	tools.ConfigurePriceProviders(priceProviders(cfg.Prices))
+	if err := tools.ConfigureFX(tools.FXConfig{
+		File:        cfg.FX.RatesFile,
+		ProviderURL: cfg.FX.ProviderURL,
+		Refresh:     cfg.FX.Refresh,
+	}); err != nil {
+		log.Fatalf("Invalid exchange rate configuration: %v", err)
+	}
+	tools.ConfigureSettings(tools.Settings{
+		Username:               cfg.BGG.Username,
+		Currency:               cfg.Defaults.Currency,
+		Destination:            cfg.Defaults.Destination,
+		SearchLimit:            cfg.Limits.SearchResults,
+		RulesMaxPages:          cfg.Limits.RulesMaxPages,
+		ForumSearchMaxPages:    cfg.Limits.ForumSearchMaxPages,
+		RecommendationMinVotes: cfg.Limits.RecommendationMinVotes,
+		LudographyLimit:        cfg.Limits.LudographyResults,
+	})
 
-	switch mode {
+	traceProvider, err := tracing.ProviderFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid tracing configuration: %v", err)
+	}
+	if traceProvider != nil {
+		tracing.SetProvider(traceProvider)
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := traceProvider.Shutdown(ctx); err != nil {
+				log.Printf("Error flushing traces: %v", err)
+			}
+		}()
+	}
+
+	mcpServer, err := createMCPServer(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch cfg.Server.Mode {
 	case "http":
-		runHTTPServer(mcpServer, port)
+		authenticator, err := buildAuthenticator(cfg)
+		if err != nil {
+			log.Fatalf("Invalid authentication configuration: %v", err)
+		}
+		runHTTPServer(mcpServer, cfg, authenticator)
 	case "stdio":
 		runStdioServer(mcpServer)
-	default:
-		log.Fatalf("Invalid mode: %s. Use 'stdio' or 'http'", mode)
 	}
 }
 
+// resolveBGGToken picks the BGG application token from the -bgg-token flag, the BGG_API_TOKEN
+// env var, or the token file (-bgg-token-file, BGG_API_TOKEN_FILE or bgg.token_file in the
+// config file), in that order.
+func resolveBGGToken(flagToken, path string) (string, error) {
+	if flagToken != "" {
+		return flagToken, nil
+	}
+	if envToken := os.Getenv("BGG_API_TOKEN"); envToken != "" {
+		return envToken, nil
+	}
+
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// resolveBGGPassword returns the BGG website password used by the write tools, from the
+// BGG_PASSWORD env var or the password file (BGG_PASSWORD_FILE or bgg.password_file). The
+// password is deliberately not accepted as a flag or config value.
+func resolveBGGPassword(path string) (string, error) {
+	if envPassword := os.Getenv("BGG_PASSWORD"); envPassword != "" {
+		return envPassword, nil
+	}
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading password file: %w", err)
+	}
+	password := strings.TrimRight(string(data), "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("password file %s is empty", path)
+	}
+	return password, nil
+}
+
+// priceProviders builds the price providers listed in the configuration, which has already
+// been validated.
+func priceProviders(cfg config.PricesConfig) []tools.PriceProvider {
+	var providers []tools.PriceProvider
+	for _, p := range cfg.Providers {
+		switch p.Type {
+		case "boardgameprices":
+			providers = append(providers, tools.NewBoardGamePricesProvider(p.Timeout))
+		case "feed", "file":
+			source := p.URL
+			if p.Type == "file" {
+				source = p.Path
+			}
+			providers = append(providers, tools.NewFeedPriceProvider(tools.FeedProviderConfig{
+				Name:     p.Name,
+				Source:   source,
+				Format:   p.Format,
+				Currency: p.Currency,
+				Country:  p.Country,
+				ShipsTo:  p.ShipsTo,
+				Timeout:  p.Timeout,
+			}))
+		}
+	}
+	return providers
+}
+
+// buildAuthenticator loads API keys from the configured key file and enables OAuth
+// protected-resource mode when MCP_OAUTH_INTROSPECTION_URL is set. With neither configured,
+// HTTP mode stays open.
+func buildAuthenticator(cfg *config.Config) (*auth.Authenticator, error) {
+	authenticator := &auth.Authenticator{}
+
+	if cfg.Server.APIKeysFile != "" {
+		keys, err := auth.LoadKeyStore(cfg.Server.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		authenticator.Keys = keys
+	}
+
+	if introspectionURL := os.Getenv("MCP_OAUTH_INTROSPECTION_URL"); introspectionURL != "" {
+		var authServers []string
+		for _, s := range strings.Split(os.Getenv("MCP_OAUTH_AUTHORIZATION_SERVERS"), ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				authServers = append(authServers, s)
+			}
+		}
+		verifier, err := auth.NewOAuthVerifier(auth.OAuthConfig{
+			Resource:             httpBaseURL(cfg) + "/mcp",
+			AuthorizationServers: authServers,
+			IntrospectionURL:     introspectionURL,
+			ClientID:             os.Getenv("MCP_OAUTH_CLIENT_ID"),
+			ClientSecret:         os.Getenv("MCP_OAUTH_CLIENT_SECRET"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		authenticator.OAuth = verifier
+	}
+
+	return authenticator, nil
+}
+
+func httpBaseURL(cfg *config.Config) string {
+	if cfg.Server.BaseURL != "" {
+		return strings.TrimSuffix(cfg.Server.BaseURL, "/")
+	}
+	return fmt.Sprintf("http://localhost:%s", cfg.Server.Port)
+}
+
 func runStdioServer(mcpServer *server.MCPServer) {
 	if err := server.ServeStdio(mcpServer); err != nil {
 		log.Fatalf("STDIO server error: %v", err)
 	}
 }
 
-func runHTTPServer(mcpServer *server.MCPServer, port string) {
-	baseURL := os.Getenv("MCP_BASE_URL")
-	if baseURL == "" {
-		baseURL = fmt.Sprintf("http://localhost:%s", port)
-	}
+func runHTTPServer(mcpServer *server.MCPServer, cfg *config.Config, authenticator *auth.Authenticator) {
+	port := cfg.Server.Port
+	baseURL := httpBaseURL(cfg)
 
 	mux := http.NewServeMux()
 
-	// Register REST endpoints
-	tools.RegisterRESTHandlers(mux)
+	// Register REST endpoints; /health stays public, /v1/bgg/* requires the rest scope
+	restMux := http.NewServeMux()
+	tools.RegisterRESTHandlers(restMux)
+	restHandler := tools.IdentityMiddleware(metrics.InstrumentHandler(restMux))
+	mux.Handle("/health", tracing.InstrumentHandler(restMux, restHandler))
+	mux.Handle("/v1/", tracing.InstrumentHandler(restMux, authenticator.Require(auth.ScopeREST, restHandler)))
+	// /metrics needs a key with the rest scope unless server.public_metrics opts out
+	if cfg.Server.PublicMetrics {
+		mux.Handle("/metrics", metrics.Default.Handler())
+	} else {
+		mux.Handle("/metrics", authenticator.Require(auth.ScopeREST, metrics.Default.Handler()))
+	}
 
-	// MCP HTTP server (streamable) mounted under /mcp
+	if authenticator.OAuth != nil {
+		mux.Handle("/.well-known/oauth-protected-resource", authenticator.OAuth.MetadataHandler())
+		mux.Handle("/.well-known/oauth-protected-resource/mcp", authenticator.OAuth.MetadataHandler())
+	}
+
+	// MCP HTTP server (streamable) mounted under /mcp. Sessions are stateful so each client's
+	// BGG username, given at initialize time, is remembered for its later requests.
 	httpServer := server.NewStreamableHTTPServer(mcpServer,
 		server.WithEndpointPath("/mcp"),
-		server.WithStateLess(true),
+		server.WithHTTPContextFunc(tools.IdentityFromRequest),
 		server.WithHeartbeatInterval(30*time.Second),
 	)
 
-	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/mcp", tracing.Handler("/mcp", authenticator.Require(auth.ScopeMCP, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Delegate to existing MCP server handler
 		httpServer.ServeHTTP(w, r)
-	})
+	}))))
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -133,9 +408,13 @@ func runHTTPServer(mcpServer *server.MCPServer, port string) {
 		}
 	}()
 
+	if authenticator.Enabled() {
+		log.Println("Authentication enabled for /mcp and /v1/bgg/* routes")
+	}
 	log.Printf("Starting HTTP server on port %s", port)
 	log.Printf("HTTP endpoint: %s/mcp", baseURL)
 	log.Printf("REST endpoints: %s/health, %s/v1/bgg/search, %s/v1/bgg/details/{id}", baseURL, baseURL, baseURL)
+	log.Printf("Metrics endpoint: %s/metrics", baseURL)
 
 	if err := serverInstance.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("HTTP server error: %v", err)
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Default is the registry exposed on /metrics.
+var Default = NewRegistry()
+
+var (
+	ToolCalls    = Default.NewCounterVec("bgg_mcp_tool_calls_total", "MCP tool calls by tool.", "tool")
+	ToolErrors   = Default.NewCounterVec("bgg_mcp_tool_errors_total", "MCP tool calls that returned an error or an error result, by tool.", "tool")
+	ToolDuration = Default.NewHistogramVec("bgg_mcp_tool_duration_seconds", "MCP tool call latency by tool.", DefaultBuckets, "tool")
+
+	RESTRequests = Default.NewCounterVec("bgg_mcp_rest_requests_total", "REST requests by route and status code.", "route", "status")
+	RESTDuration = Default.NewHistogramVec("bgg_mcp_rest_request_duration_seconds", "REST request latency by route.", DefaultBuckets, "route")
+
+	UpstreamRequests = Default.NewCounterVec("bgg_mcp_upstream_requests_total", "Upstream HTTP requests by host and status code (status is \"error\" for transport failures).", "host", "status")
+	UpstreamDuration = Default.NewHistogramVec("bgg_mcp_upstream_request_duration_seconds", "Upstream HTTP request latency by host.", DefaultBuckets, "host")
+
+	CacheLookups = Default.NewCounterVec("bgg_mcp_cache_lookups_total", "Response cache lookups by cache and result (hit or miss).", "cache", "result")
+
+	InFlight = Default.NewGaugeVec("bgg_mcp_in_flight_requests", "Requests currently being processed, by kind (tool, rest or upstream).", "kind")
+)
+
+// ToolMiddleware records call counts, errors, latency and in-flight calls for every MCP tool.
+func ToolMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		tool := request.Params.Name
+		start := time.Now()
+		InFlight.Add(1, "tool")
+		defer InFlight.Add(-1, "tool")
+
+		result, err := next(ctx, request)
+
+		ToolCalls.Inc(tool)
+		ToolDuration.Observe(time.Since(start).Seconds(), tool)
+		if err != nil || (result != nil && result.IsError) {
+			ToolErrors.Inc(tool)
+		}
+		return result, err
+	}
+}
+
+// InstrumentHandler records request counts, latency and in-flight requests for a ServeMux,
+// labelled by the matched route pattern so path parameters do not create new series.
+func InstrumentHandler(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		InFlight.Add(1, "rest")
+		defer InFlight.Add(-1, "rest")
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		_, route := mux.Handler(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		mux.ServeHTTP(rec, r)
+
+		RESTRequests.Inc(route, strconv.Itoa(rec.status))
+		RESTDuration.Observe(time.Since(start).Seconds(), route)
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestToolMiddlewareCountsErrorResults(t *testing.T) {
+	tests := []struct {
+		tool      string
+		result    *mcp.CallToolResult
+		err       error
+		wantError bool
+	}{
+		{"test-ok", mcp.NewToolResultText(`{"games": []}`), nil, false},
+		{"test-error-result", mcp.NewToolResultError("Error fetching collection: timeout"), nil, true},
+		{"test-go-error", nil, errors.New("boom"), true},
+	}
+	for _, tt := range tests {
+		handler := ToolMiddleware(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return tt.result, tt.err
+		})
+		var request mcp.CallToolRequest
+		request.Params.Name = tt.tool
+		_, _ = handler(context.Background(), request)
+	}
+
+	var out bytes.Buffer
+	Default.WriteText(&out)
+	for _, tt := range tests {
+		calls := `bgg_mcp_tool_calls_total{tool="` + tt.tool + `"} 1`
+		errs := `bgg_mcp_tool_errors_total{tool="` + tt.tool + `"} 1`
+		if !strings.Contains(out.String(), calls) {
+			t.Errorf("missing %s", calls)
+		}
+		if got := strings.Contains(out.String(), errs); got != tt.wantError {
+			t.Errorf("%s counted as an error: %t, want %t", tt.tool, got, tt.wantError)
+		}
+	}
+}
@@ -4,11 +4,12 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/kkjdanie/bgg-mcp/tools"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
-func RegisterGameRecommendationsPrompt(s *server.MCPServer) {
+func GameRecommendationsPrompt() (mcp.Prompt, server.PromptHandlerFunc) {
 	gameRecommendationPrompt := mcp.NewPrompt("game-recommendations",
 		mcp.WithPromptDescription("Get personalized board game recommendations based on your BGG collection and preferences"),
 		mcp.WithArgument("username",
@@ -16,10 +17,10 @@ func RegisterGameRecommendationsPrompt(s *server.MCPServer) {
 			mcp.RequiredArgument(),
 		),
 		mcp.WithArgument("currency",
-			mcp.ArgumentDescription("Currency for prices (USD, GBP, EUR) - default: USD"),
+			mcp.ArgumentDescription(fmt.Sprintf("Currency for prices (USD, GBP, EUR) - default: %s", tools.CurrentSettings().Currency)),
 		),
 		mcp.WithArgument("destination",
-			mcp.ArgumentDescription("Destination country (US, GB, DE) - default: US"),
+			mcp.ArgumentDescription(fmt.Sprintf("Destination country (US, GB, DE) - default: %s", tools.CurrentSettings().Destination)),
 		),
 	)
 
@@ -31,12 +32,12 @@ func RegisterGameRecommendationsPrompt(s *server.MCPServer) {
 
 		currency := request.Params.Arguments["currency"]
 		if currency == "" {
-			currency = "USD"
+			currency = tools.CurrentSettings().Currency
 		}
 
 		destination := request.Params.Arguments["destination"]
 		if destination == "" {
-			destination = "US"
+			destination = tools.CurrentSettings().Destination
 		}
 
 		return mcp.NewGetPromptResult(
@@ -52,7 +53,7 @@ func RegisterGameRecommendationsPrompt(s *server.MCPServer) {
 
 3. **Curate the list**: Consolidate the recommendations and present a final list, randomly picking a few games from each category to ensure variety in genres and mechanics.
 
-4. **Get pricing**: For each final recommendation, use the bgg-price tool to get current prices in %s currency for %s destination
+4. **Get pricing**: Use the bgg-price tool once with the IDs (or names) of all final recommendations to get their best current prices in %s currency for %s destination
 
 5. **Format the response** as shown below:
 
@@ -79,5 +80,5 @@ Based on your love of [list 2-3 of their top games], here are my recommendations
 		), nil
 	}
 
-	s.AddPrompt(gameRecommendationPrompt, gameRecommendationHandler)
+	return gameRecommendationPrompt, gameRecommendationHandler
 }
@@ -4,11 +4,12 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/kkjdanie/bgg-mcp/tools"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
-func RegisterPrompts(s *server.MCPServer) {
+func TradeSalesPrompt() (mcp.Prompt, server.PromptHandlerFunc) {
 	tradeSalesPrompt := mcp.NewPrompt("trade-sales-post",
 		mcp.WithPromptDescription("Generate a sales post for your BGG 'for trade' collection with discounted prices"),
 		mcp.WithArgument("username",
@@ -16,10 +17,13 @@ func RegisterPrompts(s *server.MCPServer) {
 			mcp.RequiredArgument(),
 		),
 		mcp.WithArgument("currency",
-			mcp.ArgumentDescription("Currency for prices (USD, GBP, EUR) - default: USD"),
+			mcp.ArgumentDescription(fmt.Sprintf("ISO 4217 currency for prices (e.g. USD, GBP, EUR, CAD, AUD) - default: %s", tools.CurrentSettings().Currency)),
 		),
 		mcp.WithArgument("destination",
-			mcp.ArgumentDescription("Destination country (US, GB, DE) - default: US"),
+			mcp.ArgumentDescription(fmt.Sprintf("Destination country (e.g. US, GB, DE, CA, AU) - default: %s", tools.CurrentSettings().Destination)),
+		),
+		mcp.WithArgument("format",
+			mcp.ArgumentDescription("Post format: markdown, bbcode (for BGG forums) or plain - default: markdown"),
 		),
 	)
 
@@ -31,12 +35,17 @@ func RegisterPrompts(s *server.MCPServer) {
 
 		currency := request.Params.Arguments["currency"]
 		if currency == "" {
-			currency = "USD"
+			currency = tools.CurrentSettings().Currency
 		}
 
 		destination := request.Params.Arguments["destination"]
 		if destination == "" {
-			destination = "US"
+			destination = tools.CurrentSettings().Destination
+		}
+
+		format := request.Params.Arguments["format"]
+		if format == "" {
+			format = tools.FormatMarkdown
 		}
 
 		return mcp.NewGetPromptResult(
@@ -44,24 +53,13 @@ func RegisterPrompts(s *server.MCPServer) {
 			[]mcp.PromptMessage{
 				mcp.NewPromptMessage(
 					mcp.RoleUser,
-					mcp.NewTextContent(fmt.Sprintf(`Please help me create a sales post for my BoardGameGeek for-trade collection. Here's what I need:
+					mcp.NewTextContent(fmt.Sprintf(`Please create a sales post for my BoardGameGeek for-trade collection.
 
-1. First, use the bgg-collection tool to fetch my collection with username "%s" and filter for games marked "fortrade"
-2. For each game in the collection, use the bgg-price tool to get current prices in %s currency for %s destination
-3. Create a formatted sales post with:
-   - A header saying "🎲 BOARD GAMES FOR SALE 🎲"
-   - List each game with its name and price (reduce prices by 20%% for a quick sale)
-   - Use emoji status indicators: 🟢 = Available, 🟡 = Pending, 🔴 = Sold (default all to 🟢)
-   - If price is not available, show "Price TBD"
-   - End with "DM for more info or bundle deals!"
-
-Format it nicely for posting in a Facebook hobby group.`, username, currency, destination)),
+Call the bgg-sale-list tool once with username "%s", currency "%s", destination "%s" and format "%s". It prices every game I have marked for trade, applies the discount and rounding, and marks games without a price as TBD. Show me the post exactly as the tool returns it, then offer to change the discount, rounding or format.`, username, currency, destination, format)),
 				),
 			},
 		), nil
 	}
 
-	s.AddPrompt(tradeSalesPrompt, tradeSalesHandler)
-
-	RegisterGameRecommendationsPrompt(s)
+	return tradeSalesPrompt, tradeSalesHandler
 }
\ No newline at end of file
@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/kkjdanie/bgg-mcp/auth"
+)
+
+// ErrBGGAccountMissing is returned by the write tools when no BGG login is configured.
+var ErrBGGAccountMissing = errors.New("writing to BGG requires a login: set BGG_USERNAME and BGG_PASSWORD (or BGG_PASSWORD_FILE)")
+
+// ErrBGGWriteNotAllowed is returned by the write tools in http mode when the caller was not
+// authenticated with the write scope.
+var ErrBGGWriteNotAllowed = errors.New("writing to BGG over HTTP requires an API key or OAuth token with the write scope")
+
+// AccountConfig is the BGG website login used by the tools that write to a user's account.
+// The XML API is read-only, so writes go through the same endpoints as the BGG website.
+type AccountConfig struct {
+	Username string
+	Password string
+	// BaseURL is the BGG website; empty means https://boardgamegeek.com. It can point at a
+	// local stand-in during development.
+	BaseURL string
+	// RequireScope only lets callers authenticated with the write scope use the login, as in
+	// http mode, where anyone who reaches the server could otherwise write to the account.
+	RequireScope bool
+}
+
+var account = &bggAccount{}
+
+// ConfigureAccount sets the BGG login used by the write tools and drops any existing session.
+func ConfigureAccount(cfg AccountConfig) {
+	account.mu.Lock()
+	defer account.mu.Unlock()
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://boardgamegeek.com"
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	account.cfg = cfg
+	account.client = nil
+}
+
+// bggAccount holds a logged-in BGG website session. The session cookies live in the client's
+// jar; a rejected request logs in again once before giving up.
+type bggAccount struct {
+	mu     sync.Mutex
+	cfg    AccountConfig
+	client *http.Client
+}
+
+// accountUsername returns the BGG user that writes are made as. When the login requires a
+// scope, the caller must be authenticated with auth.ScopeWrite; the username a client sends
+// is not proof of who it is. The user "SELF" refers to for this request must also match, so a
+// session using another account can't write to this one by mistake.
+func accountUsername(ctx context.Context) (string, error) {
+	account.mu.Lock()
+	cfg := account.cfg
+	account.mu.Unlock()
+
+	if cfg.Username == "" || cfg.Password == "" {
+		return "", ErrBGGAccountMissing
+	}
+	if cfg.RequireScope {
+		if p := auth.PrincipalFromContext(ctx); p == nil || !p.HasScope(auth.ScopeWrite) {
+			return "", ErrBGGWriteNotAllowed
+		}
+	}
+	self, err := resolveUsername(ctx, "SELF")
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(self, cfg.Username) {
+		return "", fmt.Errorf("this server writes to the BGG account %s, but this session is using %s", cfg.Username, self)
+	}
+	return cfg.Username, nil
+}
+
+// post sends payload as JSON to path on the BGG website, logging in first if needed, and
+// decodes the JSON response into out.
+func (a *bggAccount) post(ctx context.Context, path string, payload, out any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	data, err := a.send(ctx, http.MethodPost, path, "application/json", body)
+	if err != nil || out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("unexpected response from BGG for %s: %w", path, err)
+	}
+	return nil
+}
+
+// postForm sends form to path on the BGG website, as the website's older pages do, and
+// returns the response body.
+func (a *bggAccount) postForm(ctx context.Context, path string, form url.Values) ([]byte, error) {
+	return a.send(ctx, http.MethodPost, path, "application/x-www-form-urlencoded", []byte(form.Encode()))
+}
+
+// get reads path on the BGG website with the account's session, so XML API requests see the
+// account's private data. It returns errBGGQueued while BGG is still preparing the response.
+func (a *bggAccount) get(ctx context.Context, path string) ([]byte, error) {
+	return a.send(ctx, http.MethodGet, path, "", nil)
+}
+
+func (a *bggAccount) send(ctx context.Context, method, path, contentType string, body []byte) ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cfg.Username == "" || a.cfg.Password == "" {
+		return nil, ErrBGGAccountMissing
+	}
+
+	for attempt := 0; ; attempt++ {
+		if a.client == nil {
+			if err := a.login(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := a.do(ctx, method, path, contentType, body)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			a.client = nil
+			if attempt == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("BGG refused the request for %s (HTTP %d); check the account can log in on the website", a.cfg.Username, resp.StatusCode)
+		}
+		if resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("BGG returned HTTP %d for %s", resp.StatusCode, path)
+		}
+		if resp.StatusCode == http.StatusAccepted && method == http.MethodGet {
+			return nil, errBGGQueued
+		}
+		return data, nil
+	}
+}
+
+// login starts a new website session. It must be called with a.mu held.
+func (a *bggAccount) login(ctx context.Context) error {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return err
+	}
+	a.client = &http.Client{Jar: jar}
+
+	body, _ := json.Marshal(map[string]any{
+		"credentials": map[string]string{
+			"username": a.cfg.Username,
+			"password": a.cfg.Password,
+		},
+	})
+	resp, err := a.do(ctx, http.MethodPost, "/login/api/v1", "application/json", body)
+	if err != nil {
+		a.client = nil
+		return fmt.Errorf("BGG login failed: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		a.client = nil
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusBadRequest {
+			return fmt.Errorf("BGG rejected the login for %s: check BGG_USERNAME and BGG_PASSWORD", a.cfg.Username)
+		}
+		return fmt.Errorf("BGG login failed with HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (a *bggAccount) do(ctx context.Context, method, path, contentType string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, a.cfg.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	return a.client.Do(req)
+}
@@ -0,0 +1,239 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kkjdanie/bgg-mcp/auth"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// standInWebsite is a stand-in for the BGG website login, play logging and private collection
+// endpoints. Each login starts a new session; expire makes the current session rejected, as
+// when BGG drops it.
+type standInWebsite struct {
+	mu      sync.Mutex
+	logins  int
+	session string
+	plays   []geekplayRequest
+}
+
+func (s *standInWebsite) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/login/api/v1":
+		var body struct {
+			Credentials struct{ Username, Password string }
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Credentials.Password != "hunter2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		s.logins++
+		s.session = fmt.Sprintf("session-%d", s.logins)
+		http.SetCookie(w, &http.Cookie{Name: "SessionID", Value: s.session, Path: "/"})
+	case "/geekplay.php":
+		if c, err := r.Cookie("SessionID"); err != nil || c.Value != s.session {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var play geekplayRequest
+		if err := json.NewDecoder(r.Body).Decode(&play); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.plays = append(s.plays, play)
+		fmt.Fprintf(w, `{"playid": "%d", "numplays": %d}`, 100+len(s.plays), len(s.plays))
+	case "/xmlapi2/collection":
+		c, err := r.Cookie("SessionID")
+		if err != nil || c.Value != s.session || r.URL.Query().Get("showprivate") != "1" || r.URL.Query().Get("stats") != "1" {
+			// Without the session BGG leaves out the private info.
+			fmt.Fprint(w, `<items><item objectid="13" collid="7"><name>CATAN</name><status own="1"/></item></items>`)
+			return
+		}
+		fmt.Fprint(w, `<items>
+			<item objectid="13" collid="9"><name>CATAN</name><stats><rating value="N/A"/></stats><status own="0" wishlist="1" wishlistpriority="2"/></item>
+			<item objectid="13" collid="7"><name>CATAN</name><stats><rating value="8.5"/></stats><status own="1" lastmodified="2025-01-02 10:00:00"/><privateinfo pricepaid="42.50" pp_currency="GBP" acquisitiondate="2024-12-25"/></item>
+			<item objectid="822" collid="8"><name>Carcassonne</name><stats><rating value="6"/></stats><status own="1"/></item>
+		</items>`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *standInWebsite) expire() {
+	s.mu.Lock()
+	s.session = ""
+	s.mu.Unlock()
+}
+
+// useAccount points the write tools at site, logged in as alice, for the test.
+func useAccount(t *testing.T, site http.Handler, requireScope bool) {
+	t.Helper()
+	srv := httptest.NewServer(site)
+	t.Cleanup(srv.Close)
+
+	account.mu.Lock()
+	saved := account.cfg
+	account.mu.Unlock()
+	ConfigureAccount(AccountConfig{Username: "alice", Password: "hunter2", BaseURL: srv.URL, RequireScope: requireScope})
+	t.Cleanup(func() { ConfigureAccount(saved) })
+	t.Setenv("BGG_USERNAME", "alice")
+
+	// Game names in previews come from the XML API; answer those locally too.
+	client := standInUpstream(t, "", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<items><item type="boardgame" id="13"><name type="primary" value="CATAN"/></item></items>`))
+	})
+	savedClient := http.DefaultClient
+	http.DefaultClient = client
+	t.Cleanup(func() { http.DefaultClient = savedClient })
+}
+
+func logPlay(t *testing.T, ctx context.Context, confirm bool) LogPlayResult {
+	t.Helper()
+	_, handler := LogPlayTool()
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]any{
+		"id":      float64(13),
+		"date":    "2025-01-02",
+		"minutes": float64(90),
+		"players": []any{
+			map[string]any{"name": "Alice", "username": "alice", "score": "10", "win": true},
+			map[string]any{"name": "Bob", "score": "8"},
+		},
+		"confirm": confirm,
+	}
+	result, err := handler(ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := mcp.AsTextContent(result.Content[0])
+	var out LogPlayResult
+	if err := json.Unmarshal([]byte(text.Text), &out); err != nil {
+		t.Fatalf("unexpected result %q", text.Text)
+	}
+	return out
+}
+
+func TestLogPlayReusesSessionAndLogsInAgainOnce(t *testing.T) {
+	site := &standInWebsite{}
+	useAccount(t, site, false)
+	ctx := context.Background()
+
+	if preview := logPlay(t, ctx, false); preview.Status != "preview" {
+		t.Fatalf("status = %q, want preview", preview.Status)
+	}
+	if site.logins != 0 || len(site.plays) != 0 {
+		t.Fatalf("preview made %d logins and %d plays, want none", site.logins, len(site.plays))
+	}
+
+	logPlay(t, ctx, true)
+	logPlay(t, ctx, true)
+	if site.logins != 1 {
+		t.Errorf("two plays made %d logins, want the session reused", site.logins)
+	}
+
+	site.expire()
+	if result := logPlay(t, ctx, true); result.Status != "logged" || result.PlayID != "103" {
+		t.Errorf("after the session expired got %+v, want the play logged", result)
+	}
+	if site.logins != 2 {
+		t.Errorf("logins = %d, want one more after the rejection", site.logins)
+	}
+
+	if len(site.plays) != 3 {
+		t.Fatalf("BGG received %d plays, want 3", len(site.plays))
+	}
+	got := site.plays[0]
+	if got.Action != "save" || got.ObjectType != "thing" || got.ObjectID != "13" || got.PlayDate != "2025-01-02" || got.Length != 90 || got.Quantity != 1 {
+		t.Errorf("posted play = %+v", got)
+	}
+	if len(got.Players) != 2 || got.Players[0].Username != "alice" || !got.Players[0].Win || got.Players[1].Name != "Bob" || got.Players[1].Score != "8" {
+		t.Errorf("posted players = %+v", got.Players)
+	}
+}
+
+func TestLogPlayGivesUpAfterSecondRejection(t *testing.T) {
+	site := &standInWebsite{}
+	useAccount(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/geekplay.php" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		site.ServeHTTP(w, r)
+	}), false)
+
+	_, handler := LogPlayTool()
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]any{"id": float64(13), "confirm": true}
+	result, _ := handler(context.Background(), request)
+	text, _ := mcp.AsTextContent(result.Content[0])
+	if !strings.Contains(text.Text, "refused") || site.logins != 2 {
+		t.Errorf("got %q after %d logins, want a refusal after logging in twice", text.Text, site.logins)
+	}
+	if !result.IsError {
+		t.Error("the refusal was not marked as an error result")
+	}
+}
+
+func TestAccountUsernameRequiresWriteScope(t *testing.T) {
+	useAccount(t, &standInWebsite{}, true)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{"anonymous", context.Background(), ErrBGGWriteNotAllowed},
+		{"claimed username only", WithUsername(context.Background(), "alice"), ErrBGGWriteNotAllowed},
+		{"read-only key", auth.WithPrincipal(context.Background(), &auth.Principal{ID: "bot", Scopes: []string{auth.ScopeMCP}}), ErrBGGWriteNotAllowed},
+		{"write key", auth.WithPrincipal(context.Background(), &auth.Principal{ID: "alice", Scopes: []string{auth.ScopeMCP, auth.ScopeWrite}}), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			username, err := accountUsername(tt.ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && username != "alice" {
+				t.Errorf("username = %q, want alice", username)
+			}
+		})
+	}
+
+	ctx := auth.WithPrincipal(WithUsername(context.Background(), "mallory"), &auth.Principal{ID: "mallory", Scopes: []string{auth.ScopeWrite}})
+	if _, err := accountUsername(ctx); err == nil {
+		t.Error("a session using another BGG user was allowed to write")
+	}
+}
+
+func TestReadCollectionStateIncludesRatingAndPrivateInfo(t *testing.T) {
+	useAccount(t, &standInWebsite{}, false)
+
+	state, name, err := readCollectionState(context.Background(), "alice", 13)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := CollectionState{
+		InCollection:    true,
+		CollID:          7,
+		Own:             true,
+		Rating:          8.5,
+		PricePaid:       42.5,
+		PriceCurrency:   "GBP",
+		AcquisitionDate: "2024-12-25",
+		LastModified:    "2025-01-02 10:00:00",
+	}
+	if state != want || name != "CATAN" {
+		t.Errorf("state = %+v (%q), want %+v", state, name, want)
+	}
+}
@@ -0,0 +1,419 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kkjdanie/bgg-mcp/tracing"
+	"github.com/kkjdaniel/gogeek/collection"
+	"github.com/kkjdaniel/gogeek/thing"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// BudgetPlan is the set of wishlist games chosen for a budget.
+type BudgetPlan struct {
+	Username  string  `json:"username"`
+	Currency  string  `json:"currency"`
+	Budget    float64 `json:"budget"`
+	Spent     float64 `json:"spent"`
+	Remaining float64 `json:"remaining"`
+	// TotalValue is the summed value score of the chosen games.
+	TotalValue  float64           `json:"total_value"`
+	Selected    []BudgetCandidate `json:"selected"`
+	RunnersUp   []BudgetCandidate `json:"runners_up"`
+	Unpriced    []BudgetCandidate `json:"unpriced,omitempty"`
+	Explanation []string          `json:"explanation"`
+	RatesDate   string            `json:"rates_date,omitempty"`
+	Warnings    []string          `json:"warnings,omitempty"`
+}
+
+// BudgetCandidate is a wishlist game considered by the planner. Value is the priority weight
+// times the predicted rating, so a must-have game the user is likely to love scores highest.
+type BudgetCandidate struct {
+	GameID          int     `json:"game_id"`
+	Name            string  `json:"name"`
+	Priority        int     `json:"priority"`
+	BGGRating       float64 `json:"bgg_rating"`
+	PredictedRating float64 `json:"predicted_rating"`
+	Value           float64 `json:"value"`
+	Price           float64 `json:"price,omitempty"`
+	Store           string  `json:"store,omitempty"`
+	Link            string  `json:"link,omitempty"`
+	InStock         bool    `json:"in_stock"`
+	Reason          string  `json:"reason,omitempty"`
+}
+
+// wishlistPriorityWeights maps BGG wishlist priorities (1 must have ... 5 don't buy) to value
+// weights.
+var wishlistPriorityWeights = map[int]float64{1: 5, 2: 4, 3: 3, 4: 2, 5: 0}
+
+// tasteSampleSize caps the rated games read to learn the user's taste.
+const tasteSampleSize = 40
+
+func BudgetPlannerTool() (mcp.Tool, server.ToolHandlerFunc) {
+	tool := mcp.NewTool("bgg-budget-planner",
+		mcp.WithDescription("Pick the best set of games from a BoardGameGeek (BGG) user's wishlist for a budget. Each game's value combines its wishlist priority with a predicted rating based on the games the user rated highly or poorly, and current prices including shipping are used as costs. Returns the chosen games, an explanation and the runners-up."),
+		mcp.WithString("username",
+			mcp.Required(),
+			mcp.Description("The BGG username. When the user refers to themselves (me, my, I), use 'SELF' as the value."),
+		),
+		mcp.WithNumber("budget",
+			mcp.Required(),
+			mcp.Description("The amount to spend, in the chosen currency"),
+		),
+		mcp.WithString("currency",
+			mcp.Description(fmt.Sprintf("ISO 4217 currency code (default: %s)", CurrentSettings().Currency)),
+		),
+		mcp.WithString("destination",
+			mcp.Description(fmt.Sprintf("Two-letter destination country for shipping (default: %s)", CurrentSettings().Destination)),
+		),
+		mcp.WithNumber("max_priority",
+			mcp.Description("Only consider wishlist priorities up to this value, from 1 (must have) to 4 (thinking about it) (default: 4)"),
+		),
+		mcp.WithBoolean("in_stock",
+			mcp.Description("Only use prices from retailers with the game in stock (default: true)"),
+		),
+		mcp.WithNumber("runners_up",
+			mcp.Description("Number of runners-up to list (default: 5)"),
+		),
+		withFormatArgument(FormatJSON, FormatCSV, FormatMarkdown),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		arguments := request.GetArguments()
+
+		username, _ := arguments["username"].(string)
+		if username == "" {
+			return mcp.NewToolResultError("username is required"), nil
+		}
+		username, err := resolveUsername(ctx, username)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		budget, _ := arguments["budget"].(float64)
+		if budget <= 0 || math.IsInf(budget, 0) || math.IsNaN(budget) {
+			return mcp.NewToolResultError("budget must be a positive amount"), nil
+		}
+
+		query := PriceQuery{
+			Currency:    CurrentSettings().Currency,
+			Destination: CurrentSettings().Destination,
+			InStockOnly: true,
+			Summary:     true,
+		}
+		if c, ok := arguments["currency"].(string); ok && c != "" {
+			query.Currency = strings.ToUpper(c)
+		}
+		if d, ok := arguments["destination"].(string); ok && d != "" {
+			query.Destination = strings.ToUpper(d)
+		}
+		if v, ok := arguments["in_stock"].(bool); ok {
+			query.InStockOnly = v
+		}
+		maxPriority := 4
+		if p, ok := arguments["max_priority"].(float64); ok && p >= 1 && p <= 5 {
+			maxPriority = int(p)
+		}
+		runnersUp := 5
+		if r, ok := arguments["runners_up"].(float64); ok && r >= 0 {
+			runnersUp = int(r)
+		}
+
+		done := traceBGG(ctx, "collection.Query", tracing.Username("bgg.username", username), tracing.Bool("bgg.wishlist", true))
+		wishlist, err := collection.Query(username, collection.WithWishlist(true))
+		done(err)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error fetching wishlist: %v", err)), nil
+		}
+
+		var candidates []BudgetCandidate
+		seen := map[int]bool{}
+		query.Names = map[int]string{}
+		for _, item := range wishlist.Items {
+			priority := item.Status.WishlistPriority
+			if seen[item.ObjectID] || priority < 1 || priority > maxPriority || wishlistPriorityWeights[priority] == 0 {
+				continue
+			}
+			seen[item.ObjectID] = true
+			candidates = append(candidates, BudgetCandidate{GameID: item.ObjectID, Name: item.Name, Priority: priority})
+			query.IDs = append(query.IDs, item.ObjectID)
+			query.Names[item.ObjectID] = item.Name
+		}
+		if len(candidates) == 0 {
+			return mcp.NewToolResultText(fmt.Sprintf("%s has no wishlist games with priority %d or better", username, maxPriority)), nil
+		}
+		if len(candidates) > maxPriceGames {
+			return mcp.NewToolResultError(fmt.Sprintf("The wishlist has %d games; lower max_priority to plan with at most %d", len(candidates), maxPriceGames)), nil
+		}
+
+		plan := &BudgetPlan{Username: username, Currency: query.Currency, Budget: budget}
+
+		taste, err := learnTaste(ctx, username)
+		if err != nil {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("predicted ratings use BGG ratings only: %v", err))
+		}
+		things, err := fetchThings(ctx, query.IDs)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		details := map[int]thing.Item{}
+		for _, item := range things {
+			details[item.ID] = item
+		}
+		for i := range candidates {
+			c := &candidates[i]
+			var info EssentialGameInfo
+			if item, ok := details[c.GameID]; ok {
+				info = extractEssentialInfo(item)
+			}
+			c.BGGRating = round2(info.BGGRating)
+			c.PredictedRating = taste.predict(info)
+			c.Value = round2(wishlistPriorityWeights[c.Priority] * c.PredictedRating)
+		}
+
+		prices, err := fetchPrices(ctx, query)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		plan.Warnings = append(plan.Warnings, prices.Warnings...)
+		plan.RatesDate = prices.RatesDate
+		offers := map[int]*PriceOffer{}
+		for _, g := range prices.Games {
+			offers[g.GameID] = g.BestOffer
+		}
+
+		var priced []BudgetCandidate
+		for _, c := range candidates {
+			if o := offers[c.GameID]; o != nil {
+				c.Price, c.Store, c.Link, c.InStock = o.Total, o.Store, o.Link, o.InStock
+				priced = append(priced, c)
+			} else {
+				c.Reason = "no current price"
+				plan.Unpriced = append(plan.Unpriced, c)
+			}
+		}
+
+		chosen := knapsack(priced, budget)
+		planBudget(plan, priced, chosen, runnersUp)
+
+		return formatToolResult(formatArgument(arguments), plan, tableFor(func() table { return budgetTable(plan) }))
+	}
+
+	return tool, handler
+}
+
+// maxKnapsackBuckets bounds the budget steps knapsack works in, and so its memory.
+const maxKnapsackBuckets = 10000
+
+// knapsack picks the candidates with the greatest total value whose prices fit the budget.
+// It works in steps of 0.01, or of a larger fraction of the budget when that would take more
+// than maxKnapsackBuckets steps. Prices are rounded up to a whole step, so the choice never goes
+// over budget.
+func knapsack(candidates []BudgetCandidate, budget float64) map[int]bool {
+	var fits []BudgetCandidate
+	total := 0.0
+	for _, c := range candidates {
+		if c.Price <= budget {
+			fits = append(fits, c)
+			total += c.Price
+		}
+	}
+	chosen := map[int]bool{}
+	if total <= budget {
+		for _, c := range fits {
+			chosen[c.GameID] = true
+		}
+		return chosen
+	}
+
+	step := math.Max(0.01, budget/maxKnapsackBuckets)
+	capacity := int(math.Floor(budget / step))
+	costs := make([]int, len(fits))
+	for i, c := range fits {
+		costs[i] = int(math.Ceil(c.Price / step))
+	}
+	if step == 0.01 {
+		// Work in whole cents: 1.13/0.01 is 112.99..., which would drop an exact fit.
+		capacity = int(math.Round(budget * 100))
+		for i, c := range fits {
+			costs[i] = int(math.Round(c.Price * 100))
+		}
+	}
+
+	// best[w] is the greatest value within w steps; took[i][w] records whether fits[i] is in it.
+	best := make([]float64, capacity+1)
+	took := make([][]bool, len(fits))
+	for i, c := range fits {
+		took[i] = make([]bool, capacity+1)
+		for w := capacity; w >= costs[i]; w-- {
+			if v := best[w-costs[i]] + c.Value; v > best[w] {
+				best[w] = v
+				took[i][w] = true
+			}
+		}
+	}
+
+	w := capacity
+	for i := len(fits) - 1; i >= 0; i-- {
+		if took[i][w] {
+			chosen[fits[i].GameID] = true
+			w -= costs[i]
+		}
+	}
+	return chosen
+}
+
+// planBudget fills in the chosen games, runners-up and explanation.
+func planBudget(plan *BudgetPlan, priced []BudgetCandidate, chosen map[int]bool, runnersUp int) {
+	plan.Selected = []BudgetCandidate{}
+	plan.RunnersUp = []BudgetCandidate{}
+	var rest []BudgetCandidate
+	for _, c := range priced {
+		if chosen[c.GameID] {
+			plan.Selected = append(plan.Selected, c)
+			plan.Spent = round2(plan.Spent + c.Price)
+			plan.TotalValue = round2(plan.TotalValue + c.Value)
+		} else {
+			rest = append(rest, c)
+		}
+	}
+	plan.Remaining = round2(plan.Budget - plan.Spent)
+
+	byValue := func(list []BudgetCandidate) {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Value > list[j].Value })
+	}
+	byValue(plan.Selected)
+	byValue(rest)
+
+	for _, c := range rest {
+		if len(plan.RunnersUp) == runnersUp {
+			break
+		}
+		switch {
+		case c.Price > plan.Budget:
+			c.Reason = fmt.Sprintf("costs %s on its own, over the budget", formatDecimal(c.Price))
+		case c.Price > plan.Remaining:
+			c.Reason = fmt.Sprintf("needs %s more than is left", formatDecimal(round2(c.Price-plan.Remaining)))
+		default:
+			c.Reason = "fits the remaining budget but adds little value"
+		}
+		plan.RunnersUp = append(plan.RunnersUp, c)
+	}
+
+	if len(plan.Selected) == 0 {
+		plan.Explanation = append(plan.Explanation, fmt.Sprintf("No priced wishlist game fits a budget of %s %s.", formatDecimal(plan.Budget), plan.Currency))
+	} else {
+		plan.Explanation = append(plan.Explanation, fmt.Sprintf("%d games for %s of the %s %s budget, the highest total value of any combination that fits.",
+			len(plan.Selected), formatDecimal(plan.Spent), formatDecimal(plan.Budget), plan.Currency))
+		top := plan.Selected[0]
+		plan.Explanation = append(plan.Explanation, fmt.Sprintf("%s has the most value: wishlist priority %d and a predicted rating of %.1f (BGG %.1f).",
+			top.Name, top.Priority, top.PredictedRating, top.BGGRating))
+	}
+	for _, c := range rest {
+		switch {
+		case c.Priority != 1:
+		case c.Price > plan.Budget:
+			plan.Explanation = append(plan.Explanation, fmt.Sprintf("Must-have %s was left out: it costs %s on its own, over the budget.", c.Name, formatDecimal(c.Price)))
+		default:
+			plan.Explanation = append(plan.Explanation, fmt.Sprintf("Must-have %s was left out: at %s it would crowd out more value than it adds.", c.Name, formatDecimal(c.Price)))
+		}
+	}
+	if len(plan.Unpriced) > 0 {
+		plan.Explanation = append(plan.Explanation, fmt.Sprintf("%d wishlist games have no current price and were not considered.", len(plan.Unpriced)))
+	}
+	plan.Explanation = append(plan.Explanation, "Value is the wishlist priority weight (5 for must have down to 2 for thinking about it) times the predicted rating.")
+}
+
+// tasteProfile scores mechanics and categories by how often they appear in games the user
+// rated 8 or more versus 5 or less.
+type tasteProfile struct {
+	scores map[string]float64
+}
+
+func learnTaste(ctx context.Context, username string) (tasteProfile, error) {
+	profile := tasteProfile{scores: map[string]float64{}}
+
+	counts := map[string][2]int{}
+	for i, filter := range []collection.CollectionOption{collection.WithMinRating(8), collection.WithMaxRating(5)} {
+		done := traceBGG(ctx, "collection.Query", tracing.Username("bgg.username", username), tracing.Bool("bgg.rated", true))
+		rated, err := collection.Query(username, collection.WithRated(true), filter)
+		done(err)
+		if err != nil {
+			return profile, err
+		}
+
+		var ids []int
+		for _, item := range rated.Items {
+			if len(ids) == tasteSampleSize {
+				break
+			}
+			ids = append(ids, item.ObjectID)
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		things, err := fetchThings(ctx, ids)
+		if err != nil {
+			return profile, err
+		}
+		for _, item := range things {
+			info := extractEssentialInfo(item)
+			for _, tag := range tasteTags(info) {
+				c := counts[tag]
+				c[i]++
+				counts[tag] = c
+			}
+		}
+	}
+
+	// Smoothed so a tag seen once doesn't dominate: (liked - disliked) / (liked + disliked + 2).
+	for tag, c := range counts {
+		profile.scores[tag] = float64(c[0]-c[1]) / float64(c[0]+c[1]+2)
+	}
+	return profile, nil
+}
+
+// predict adjusts the game's BGG rating by up to two points towards the user's taste, based
+// on the average score of the mechanics and categories the user has rated games with.
+func (t tasteProfile) predict(info EssentialGameInfo) float64 {
+	rating := info.BGGRating
+	if rating == 0 {
+		rating = 6
+	}
+	var sum float64
+	var n int
+	for _, tag := range tasteTags(info) {
+		if score, ok := t.scores[tag]; ok {
+			sum += score
+			n++
+		}
+	}
+	if n > 0 {
+		rating += 2 * sum / float64(n)
+	}
+	return round2(math.Max(1, math.Min(10, rating)))
+}
+
+func tasteTags(info EssentialGameInfo) []string {
+	tags := make([]string, 0, len(info.Mechanics)+len(info.Categories))
+	tags = append(tags, info.Mechanics...)
+	return append(tags, info.Categories...)
+}
+
+func budgetTable(plan *BudgetPlan) table {
+	t := table{Headers: []string{"Pick", "ID", "Name", "Priority", "Predicted Rating", "Value", "Price", "Currency", "Store", "Note"}}
+	add := func(pick string, list []BudgetCandidate) {
+		for _, c := range list {
+			t.Rows = append(t.Rows, []string{pick, strconv.Itoa(c.GameID), c.Name, strconv.Itoa(c.Priority), formatDecimal(c.PredictedRating), formatDecimal(c.Value), formatDecimal(c.Price), plan.Currency, c.Store, c.Reason})
+		}
+	}
+	add("yes", plan.Selected)
+	add("runner-up", plan.RunnersUp)
+	add("no price", plan.Unpriced)
+	return t
+}
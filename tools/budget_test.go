@@ -0,0 +1,15 @@
+package tools
+
+import "testing"
+
+func TestKnapsackTakesExactFit(t *testing.T) {
+	candidates := []BudgetCandidate{
+		{GameID: 1, Price: 1.06, Value: 2},
+		{GameID: 2, Price: 0.07, Value: 1},
+		{GameID: 3, Price: 1.00, Value: 1.5},
+	}
+	chosen := knapsack(candidates, 1.13)
+	if !chosen[1] || !chosen[2] || chosen[3] {
+		t.Errorf("chose %v, want games 1 and 2, which cost exactly the budget", chosen)
+	}
+}
@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// responseCache is a size-bounded LRU cache of upstream response bodies with a fixed TTL.
+type responseCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List // front = most recently used
+}
+
+type cachedResponse struct {
+	key        string
+	statusCode int
+	header     map[string][]string
+	body       []byte
+	expires    time.Time
+}
+
+func newResponseCache(ttl time.Duration, maxEntries int) *responseCache {
+	return &responseCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    map[string]*list.Element{},
+		order:      list.New(),
+	}
+}
+
+func (c *responseCache) get(key string) (*cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*cachedResponse)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return entry, true
+}
+
+func (c *responseCache) put(entry *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry.expires = time.Now().Add(c.ttl)
+	if el, ok := c.entries[entry.key]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[entry.key] = c.order.PushFront(entry)
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedResponse).key)
+	}
+}
+
+// clear drops every cached response, e.g. after a write changes upstream state.
+func (c *responseCache) clear() {
+	c.mu.Lock()
+	c.entries = map[string]*list.Element{}
+	c.order.Init()
+	c.mu.Unlock()
+}
+
+// removeIf drops the cached responses whose key matches.
+func (c *responseCache) removeIf(match func(key string) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, el := range c.entries {
+		if match(key) {
+			c.order.Remove(el)
+			delete(c.entries, key)
+		}
+	}
+}
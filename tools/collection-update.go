@@ -0,0 +1,567 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/kkjdanie/bgg-mcp/tracing"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// CollectionState is a game's status in the account's collection, including the personal
+// rating and the private info only the account can see.
+type CollectionState struct {
+	InCollection     bool    `json:"in_collection"`
+	CollID           int     `json:"coll_id,omitempty"`
+	Own              bool    `json:"own"`
+	PrevOwned        bool    `json:"prev_owned"`
+	ForTrade         bool    `json:"for_trade"`
+	Want             bool    `json:"want"`
+	WantToPlay       bool    `json:"want_to_play"`
+	WantToBuy        bool    `json:"want_to_buy"`
+	Wishlist         bool    `json:"wishlist"`
+	WishlistPriority int     `json:"wishlist_priority,omitempty"`
+	Preordered       bool    `json:"preordered"`
+	Rating           float64 `json:"rating,omitempty"`
+	PricePaid        float64 `json:"price_paid,omitempty"`
+	PriceCurrency    string  `json:"price_currency,omitempty"`
+	AcquisitionDate  string  `json:"acquisition_date,omitempty"`
+	LastModified     string  `json:"last_modified,omitempty"`
+}
+
+// accountCollectionXML is the part of a collection?stats=1&showprivate=1 response read back
+// after a change.
+type accountCollectionXML struct {
+	Items []struct {
+		ObjectID int    `xml:"objectid,attr"`
+		CollID   int    `xml:"collid,attr"`
+		Name     string `xml:"name"`
+		Stats    struct {
+			Rating struct {
+				Value string `xml:"value,attr"`
+			} `xml:"rating"`
+		} `xml:"stats"`
+		Status struct {
+			Own              int    `xml:"own,attr"`
+			PrevOwned        int    `xml:"prevowned,attr"`
+			ForTrade         int    `xml:"fortrade,attr"`
+			Want             int    `xml:"want,attr"`
+			WantToPlay       int    `xml:"wanttoplay,attr"`
+			WantToBuy        int    `xml:"wanttobuy,attr"`
+			Wishlist         int    `xml:"wishlist,attr"`
+			WishlistPriority int    `xml:"wishlistpriority,attr"`
+			Preordered       int    `xml:"preordered,attr"`
+			LastModified     string `xml:"lastmodified,attr"`
+		} `xml:"status"`
+		PrivateInfo struct {
+			PricePaid       string `xml:"pricepaid,attr"`
+			Currency        string `xml:"pp_currency,attr"`
+			AcquisitionDate string `xml:"acquisitiondate,attr"`
+		} `xml:"privateinfo"`
+	} `xml:"item"`
+}
+
+type CollectionChangeResult struct {
+	Status   string           `json:"status"`
+	Account  string           `json:"account"`
+	GameID   int              `json:"game_id"`
+	GameName string           `json:"game_name,omitempty"`
+	Changes  map[string]any   `json:"changes"`
+	Before   CollectionState  `json:"before"`
+	After    *CollectionState `json:"after,omitempty"`
+	Message  string           `json:"message"`
+}
+
+// collectionStatusFlags maps the status arguments of bgg-collection-update to the form fields
+// of geekcollection.php. They are the same flags bgg-collection filters on.
+var collectionStatusFlags = map[string]string{
+	"owned":      "own",
+	"prevowned":  "prevowned",
+	"fortrade":   "fortrade",
+	"want":       "want",
+	"wanttoplay": "wanttoplay",
+	"wanttobuy":  "wanttobuy",
+	"wishlist":   "wishlist",
+	"preordered": "preordered",
+}
+
+func CollectionUpdateTool() (mcp.Tool, server.ToolHandlerFunc) {
+	tool := mcp.NewTool("bgg-collection-update",
+		mcp.WithDescription("Add a game to the user's BoardGameGeek (BGG) collection or change its status flags, personal rating or private info. Without confirm=true this only returns a preview and writes nothing: show the preview to the user and call again with confirm=true only after they agree. Returns the collection state, including the personal rating and private info, before and after the change."),
+		mcp.WithTitleAnnotation("Update a game in the BGG collection"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
+		mcp.WithString("name",
+			mcp.Description("The name of the board game"),
+		),
+		mcp.WithNumber("id",
+			mcp.Description("The BoardGameGeek ID of the board game (preferred over name)"),
+		),
+		mcp.WithBoolean("owned",
+			mcp.Description("Mark the game as owned (or not)"),
+		),
+		mcp.WithBoolean("prevowned",
+			mcp.Description("Mark the game as previously owned"),
+		),
+		mcp.WithBoolean("fortrade",
+			mcp.Description("Mark the game as for trade"),
+		),
+		mcp.WithBoolean("want",
+			mcp.Description("Mark the game as wanted in trade"),
+		),
+		mcp.WithBoolean("wanttoplay",
+			mcp.Description("Mark the game as want to play"),
+		),
+		mcp.WithBoolean("wanttobuy",
+			mcp.Description("Mark the game as want to buy"),
+		),
+		mcp.WithBoolean("wishlist",
+			mcp.Description("Add the game to (or remove it from) the wishlist"),
+		),
+		mcp.WithNumber("wishlist_priority",
+			mcp.Description("Wishlist priority from 1 (must have) to 5 (don't buy this); implies wishlist=true"),
+		),
+		mcp.WithBoolean("preordered",
+			mcp.Description("Mark the game as preordered"),
+		),
+		mcp.WithNumber("rating",
+			mcp.Description("Personal rating from 1 to 10"),
+		),
+		mcp.WithNumber("price_paid",
+			mcp.Description("Private info: price paid"),
+		),
+		mcp.WithString("price_currency",
+			mcp.Description(fmt.Sprintf("Private info: currency of the price paid (default: %s)", CurrentSettings().Currency)),
+		),
+		mcp.WithString("acquisition_date",
+			mcp.Description("Private info: date acquired as YYYY-MM-DD"),
+		),
+		mcp.WithBoolean("confirm",
+			mcp.Description("Set to true to write the change to BGG. Only set this after the user has approved the preview."),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		arguments := request.GetArguments()
+
+		username, err := accountUsername(ctx)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		changes, err := parseCollectionChanges(arguments)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if len(changes) == 0 {
+			return mcp.NewToolResultError("No changes given: set at least one status flag, rating or private info field"), nil
+		}
+
+		gameID, gameName, before, err := collectionTarget(ctx, username, arguments)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		result := CollectionChangeResult{
+			Account:  username,
+			GameID:   gameID,
+			GameName: gameName,
+			Changes:  changes,
+			Before:   before,
+		}
+
+		confirm, _ := arguments["confirm"].(bool)
+		if !confirm {
+			result.Status = "preview"
+			result.Message = "Nothing has been written to BGG. Show these changes to the user and call bgg-collection-update again with the same arguments and confirm=true once they approve them."
+			out, _ := json.Marshal(result)
+			return mcp.NewToolResultText(string(out)), nil
+		}
+
+		done := traceBGG(ctx, "geekcollection.update",
+			tracing.Int("bgg.id", gameID),
+			tracing.Username("bgg.username", username),
+		)
+		after, err := applyCollectionChanges(ctx, username, gameID, before, changes)
+		done(err)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		result.Status = "updated"
+		result.After = &after
+		result.Message = fmt.Sprintf("Updated %s in %s's collection.", gameName, username)
+		out, _ := json.Marshal(result)
+		return mcp.NewToolResultText(string(out)), nil
+	}
+
+	return tool, handler
+}
+
+func CollectionRemoveTool() (mcp.Tool, server.ToolHandlerFunc) {
+	tool := mcp.NewTool("bgg-collection-remove",
+		mcp.WithDescription("Remove a game from the user's BoardGameGeek (BGG) collection, deleting its status, rating, comments and private info. Without confirm=true this only returns a preview and writes nothing: show the preview to the user and call again with confirm=true only after they agree."),
+		mcp.WithTitleAnnotation("Remove a game from the BGG collection"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithOpenWorldHintAnnotation(true),
+		mcp.WithString("name",
+			mcp.Description("The name of the board game"),
+		),
+		mcp.WithNumber("id",
+			mcp.Description("The BoardGameGeek ID of the board game (preferred over name)"),
+		),
+		mcp.WithBoolean("confirm",
+			mcp.Description("Set to true to remove the game. Only set this after the user has approved the preview."),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		arguments := request.GetArguments()
+
+		username, err := accountUsername(ctx)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		gameID, gameName, before, err := collectionTarget(ctx, username, arguments)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if !before.InCollection {
+			return mcp.NewToolResultError(fmt.Sprintf("%s is not in %s's collection", gameName, username)), nil
+		}
+
+		result := CollectionChangeResult{
+			Account:  username,
+			GameID:   gameID,
+			GameName: gameName,
+			Changes:  map[string]any{"remove": true},
+			Before:   before,
+		}
+
+		confirm, _ := arguments["confirm"].(bool)
+		if !confirm {
+			result.Status = "preview"
+			result.Message = "Nothing has been written to BGG. Confirm with the user that they want to remove this game, then call bgg-collection-remove again with confirm=true."
+			out, _ := json.Marshal(result)
+			return mcp.NewToolResultText(string(out)), nil
+		}
+
+		done := traceBGG(ctx, "geekcollection.delete",
+			tracing.Int("bgg.id", gameID),
+			tracing.Username("bgg.username", username),
+		)
+		_, err = account.postForm(ctx, "/geekcollection.php", url.Values{
+			"ajax":       {"1"},
+			"action":     {"delete"},
+			"collid":     {strconv.Itoa(before.CollID)},
+			"objecttype": {"thing"},
+			"objectid":   {strconv.Itoa(gameID)},
+		})
+		done(err)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		invalidateUpstreamCache(username)
+
+		after, err := fetchCollectionState(ctx, username, gameID)
+		if err != nil {
+			return mcp.NewToolResultText(fmt.Sprintf("Removed %s, but reading the collection back failed: %v", gameName, err)), nil
+		}
+
+		result.Status = "removed"
+		result.After = &after
+		result.Message = fmt.Sprintf("Removed %s from %s's collection.", gameName, username)
+		out, _ := json.Marshal(result)
+		return mcp.NewToolResultText(string(out)), nil
+	}
+
+	return tool, handler
+}
+
+// parseCollectionChanges reads the requested changes, keyed by argument name.
+func parseCollectionChanges(arguments map[string]interface{}) (map[string]any, error) {
+	changes := map[string]any{}
+
+	for arg := range collectionStatusFlags {
+		if v, ok := arguments[arg].(bool); ok {
+			changes[arg] = v
+		}
+	}
+	if p, ok := arguments["wishlist_priority"].(float64); ok {
+		if p < 1 || p > 5 {
+			return nil, fmt.Errorf("wishlist_priority must be between 1 and 5")
+		}
+		if wishlist, ok := changes["wishlist"].(bool); ok && !wishlist {
+			return nil, fmt.Errorf("wishlist_priority can't be set while removing the game from the wishlist")
+		}
+		changes["wishlist"] = true
+		changes["wishlist_priority"] = int(p)
+	}
+	if r, ok := arguments["rating"].(float64); ok {
+		if r < 1 || r > 10 {
+			return nil, fmt.Errorf("rating must be between 1 and 10")
+		}
+		changes["rating"] = r
+	}
+	if p, ok := arguments["price_paid"].(float64); ok {
+		if p < 0 {
+			return nil, fmt.Errorf("price_paid must not be negative")
+		}
+		changes["price_paid"] = p
+		currency := CurrentSettings().Currency
+		if c, ok := arguments["price_currency"].(string); ok && c != "" {
+			currency = c
+		}
+		changes["price_currency"] = currency
+	}
+	if d, ok := arguments["acquisition_date"].(string); ok && d != "" {
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return nil, fmt.Errorf("invalid acquisition_date '%s': use YYYY-MM-DD", d)
+		}
+		changes["acquisition_date"] = d
+	}
+
+	return changes, nil
+}
+
+// collectionTarget resolves the game named in arguments and reads its current state in the
+// user's collection.
+func collectionTarget(ctx context.Context, username string, arguments map[string]interface{}) (int, string, CollectionState, error) {
+	gameID, gameName, err := resolveGame(ctx, arguments)
+	if err != nil {
+		return 0, "", CollectionState{}, err
+	}
+
+	// A cached collection may predate changes made on the website.
+	invalidateUpstreamCache(username)
+	before, name, err := readCollectionState(ctx, username, gameID)
+	if err != nil {
+		return 0, "", CollectionState{}, err
+	}
+	if gameName == "" {
+		gameName = name
+	}
+	if gameName == "" {
+		if items, err := fetchThings(ctx, []int{gameID}); err == nil && len(items) > 0 && len(items[0].Name) > 0 {
+			gameName = items[0].Name[0].Value
+		}
+	}
+	if gameName == "" {
+		gameName = fmt.Sprintf("game %d", gameID)
+	}
+	return gameID, gameName, before, nil
+}
+
+// applyCollectionChanges writes changes for gameID, adding it to the collection first when
+// needed, and returns the state read back afterwards.
+func applyCollectionChanges(ctx context.Context, username string, gameID int, before CollectionState, changes map[string]any) (CollectionState, error) {
+	collID := before.CollID
+	if !before.InCollection {
+		if _, err := account.postForm(ctx, "/geekcollection.php", url.Values{
+			"ajax":       {"1"},
+			"action":     {"additem"},
+			"objecttype": {"thing"},
+			"objectid":   {strconv.Itoa(gameID)},
+		}); err != nil {
+			return CollectionState{}, fmt.Errorf("adding the game to the collection failed: %w", err)
+		}
+		invalidateUpstreamCache(username)
+		added, err := fetchCollectionState(ctx, username, gameID)
+		if err != nil {
+			return CollectionState{}, fmt.Errorf("the game was added but reading it back failed: %w", err)
+		}
+		if !added.InCollection {
+			return CollectionState{}, fmt.Errorf("BGG did not add the game to the collection")
+		}
+		collID = added.CollID
+	}
+
+	base := url.Values{
+		"ajax":       {"1"},
+		"action":     {"savedata"},
+		"collid":     {strconv.Itoa(collID)},
+		"objecttype": {"thing"},
+		"objectid":   {strconv.Itoa(gameID)},
+	}
+	save := func(fieldname string, fields url.Values) error {
+		form := url.Values{"fieldname": {fieldname}}
+		for k, v := range base {
+			form[k] = v
+		}
+		for k, v := range fields {
+			form[k] = v
+		}
+		_, err := account.postForm(ctx, "/geekcollection.php", form)
+		if err != nil {
+			return fmt.Errorf("saving %s failed: %w", fieldname, err)
+		}
+		return nil
+	}
+
+	// BGG saves every status flag together, so flags that were not asked to change keep their
+	// current values.
+	if statusChanged(changes) {
+		current := map[string]bool{
+			"owned":      before.Own,
+			"prevowned":  before.PrevOwned,
+			"fortrade":   before.ForTrade,
+			"want":       before.Want,
+			"wanttoplay": before.WantToPlay,
+			"wanttobuy":  before.WantToBuy,
+			"wishlist":   before.Wishlist,
+			"preordered": before.Preordered,
+		}
+		fields := url.Values{}
+		for arg, field := range collectionStatusFlags {
+			value := current[arg]
+			if v, ok := changes[arg].(bool); ok {
+				value = v
+			}
+			if value {
+				fields.Set(field, "1")
+			} else {
+				fields.Set(field, "0")
+			}
+		}
+		priority := before.WishlistPriority
+		if p, ok := changes["wishlist_priority"].(int); ok {
+			priority = p
+		}
+		if priority == 0 {
+			priority = 3
+		}
+		fields.Set("wishlistpriority", strconv.Itoa(priority))
+		if err := save("status", fields); err != nil {
+			return CollectionState{}, err
+		}
+	}
+
+	if r, ok := changes["rating"].(float64); ok {
+		if err := save("rating", url.Values{"rating": {strconv.FormatFloat(r, 'f', -1, 64)}}); err != nil {
+			return CollectionState{}, err
+		}
+	}
+
+	if _, hasPrice := changes["price_paid"]; hasPrice || changes["acquisition_date"] != nil {
+		fields := url.Values{}
+		if p, ok := changes["price_paid"].(float64); ok {
+			fields.Set("pricepaid", strconv.FormatFloat(p, 'f', 2, 64))
+			fields.Set("pp_currency", fmt.Sprint(changes["price_currency"]))
+		}
+		if d, ok := changes["acquisition_date"].(string); ok {
+			fields.Set("acquisitiondate", d)
+		}
+		if err := save("ownership", fields); err != nil {
+			return CollectionState{}, err
+		}
+	}
+
+	invalidateUpstreamCache(username)
+	return fetchCollectionState(ctx, username, gameID)
+}
+
+func statusChanged(changes map[string]any) bool {
+	for arg := range collectionStatusFlags {
+		if _, ok := changes[arg]; ok {
+			return true
+		}
+	}
+	_, ok := changes["wishlist_priority"]
+	return ok
+}
+
+func fetchCollectionState(ctx context.Context, username string, gameID int) (CollectionState, error) {
+	state, _, err := readCollectionState(ctx, username, gameID)
+	return state, err
+}
+
+// readCollectionState returns gameID's state in the user's collection and its name there.
+// The collection is read with the account's session, since the personal rating needs stats=1
+// and the private info needs showprivate=1 from the owner. The XML API can't filter by game,
+// so the whole collection is read.
+func readCollectionState(ctx context.Context, username string, gameID int) (CollectionState, string, error) {
+	path := "/xmlapi2/collection?" + url.Values{
+		"username":    {username},
+		"stats":       {"1"},
+		"showprivate": {"1"},
+	}.Encode()
+
+	done := traceBGG(ctx, "collection.private", tracing.Username("bgg.username", username))
+	var data []byte
+	var err error
+	for attempt := 0; attempt < 4; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				done(ctx.Err())
+				return CollectionState{}, "", ctx.Err()
+			case <-time.After(time.Duration(attempt) * 2 * time.Second):
+			}
+		}
+		if data, err = account.get(ctx, path); err != errBGGQueued {
+			break
+		}
+	}
+	var result accountCollectionXML
+	if err == nil {
+		if xmlErr := xml.Unmarshal(data, &result); xmlErr != nil {
+			err = fmt.Errorf("error parsing response: %w", xmlErr)
+		}
+	}
+	done(err)
+	if err == errBGGQueued {
+		return CollectionState{}, "", fmt.Errorf("BGG is still preparing %s's collection; try again in a few seconds", username)
+	}
+	if err != nil {
+		return CollectionState{}, "", fmt.Errorf("error fetching collection: %v", err)
+	}
+
+	var matches []int
+	for i, item := range result.Items {
+		if item.ObjectID == gameID {
+			matches = append(matches, i)
+		}
+	}
+	if len(matches) == 0 {
+		return CollectionState{}, "", nil
+	}
+	// A game can appear more than once (e.g. two copies); the oldest entry is the one edited.
+	sort.Slice(matches, func(i, j int) bool { return result.Items[matches[i]].CollID < result.Items[matches[j]].CollID })
+
+	item := result.Items[matches[0]]
+	state := CollectionState{
+		InCollection:     true,
+		CollID:           item.CollID,
+		Own:              item.Status.Own == 1,
+		PrevOwned:        item.Status.PrevOwned == 1,
+		ForTrade:         item.Status.ForTrade == 1,
+		Want:             item.Status.Want == 1,
+		WantToPlay:       item.Status.WantToPlay == 1,
+		WantToBuy:        item.Status.WantToBuy == 1,
+		Wishlist:         item.Status.Wishlist == 1,
+		WishlistPriority: item.Status.WishlistPriority,
+		Preordered:       item.Status.Preordered == 1,
+		PriceCurrency:    item.PrivateInfo.Currency,
+		AcquisitionDate:  item.PrivateInfo.AcquisitionDate,
+		LastModified:     item.Status.LastModified,
+	}
+	// Unrated games report "N/A".
+	state.Rating, _ = strconv.ParseFloat(item.Stats.Rating.Value, 64)
+	state.PricePaid, _ = strconv.ParseFloat(item.PrivateInfo.PricePaid, 64)
+	if state.PricePaid == 0 {
+		state.PriceCurrency = ""
+	}
+	return state, item.Name, nil
+}
@@ -0,0 +1,362 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kkjdanie/bgg-mcp/tracing"
+	"github.com/kkjdaniel/gogeek/collection"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type CollectionValueReport struct {
+	Username    string `json:"username"`
+	Currency    string `json:"currency"`
+	Destination string `json:"destination"`
+	// RatesDate is the date of the exchange rates used when any price was converted.
+	RatesDate    string       `json:"rates_date,omitempty"`
+	Games        int          `json:"games"`
+	Priced       int          `json:"priced"`
+	TotalValue   float64      `json:"total_value"`
+	RetailValue  float64      `json:"retail_value"`
+	UsedValue    float64      `json:"used_value"`
+	ByPublisher  []ValueGroup `json:"by_publisher"`
+	ByCategory   []ValueGroup `json:"by_category"`
+	MostValuable []ValuedGame `json:"most_valuable"`
+	Missing      []ValuedGame `json:"missing"`
+	Items        []ValuedGame `json:"items,omitempty"`
+	Warnings     []string     `json:"warnings,omitempty"`
+}
+
+// ValuedGame is one owned game with its replacement value: the best retail price, or the
+// median GeekMarket asking price when no retailer lists it. Used is filled in either way.
+type ValuedGame struct {
+	GameID     int      `json:"game_id"`
+	Name       string   `json:"name"`
+	Year       int      `json:"year,omitempty"`
+	Publisher  string   `json:"publisher,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	Copies     int      `json:"copies"`
+	Retail     float64  `json:"retail,omitempty"`
+	Store      string   `json:"store,omitempty"`
+	Link       string   `json:"link,omitempty"`
+	Used       float64  `json:"used,omitempty"`
+	UsedCount  int      `json:"used_listings,omitempty"`
+	Value      float64  `json:"value"`
+	Source     string   `json:"source"`
+}
+
+// ValueGroup totals the value of the games sharing a publisher or category.
+type ValueGroup struct {
+	Name  string  `json:"name"`
+	Games int     `json:"games"`
+	Value float64 `json:"value"`
+}
+
+func CollectionValueTool() (mcp.Tool, server.ToolHandlerFunc) {
+	tool := mcp.NewTool("bgg-collection-value",
+		mcp.WithDescription("Estimate the replacement value of a BoardGameGeek (BGG) user's owned collection from current retail prices, falling back to used GeekMarket prices. Reports the total, value by publisher and by category, the most valuable games and games with no price data. Use format csv or markdown for an itemised insurance report."),
+		mcp.WithString("username",
+			mcp.Required(),
+			mcp.Description("The BGG username. When the user refers to themselves (me, my, I), use 'SELF' as the value."),
+		),
+		mcp.WithString("currency",
+			mcp.Description(fmt.Sprintf("ISO 4217 currency code for the report (default: %s)", CurrentSettings().Currency)),
+		),
+		mcp.WithString("destination",
+			mcp.Description(fmt.Sprintf("Two-letter country the retail prices are for (default: %s)", CurrentSettings().Destination)),
+		),
+		mcp.WithBoolean("include_used",
+			mcp.Description("Look up median GeekMarket asking prices for every game, used as the value of games no retailer lists (default: true)"),
+		),
+		mcp.WithBoolean("include_expansions",
+			mcp.Description("Include expansions in the valuation (default: true)"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of most valuable games and groups to list (default: 10)"),
+		),
+		mcp.WithBoolean("items",
+			mcp.Description("Include every game in the JSON report, not just the most valuable (default: false; csv and markdown always list every game)"),
+		),
+		withFormatArgument(FormatJSON, FormatCSV, FormatMarkdown),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		arguments := request.GetArguments()
+
+		username, _ := arguments["username"].(string)
+		if username == "" {
+			return mcp.NewToolResultError("username is required"), nil
+		}
+		username, err := resolveUsername(ctx, username)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		query := PriceQuery{
+			Currency:    CurrentSettings().Currency,
+			Destination: CurrentSettings().Destination,
+			Summary:     true,
+		}
+		if c, ok := arguments["currency"].(string); ok && c != "" {
+			query.Currency = strings.ToUpper(c)
+		}
+		if d, ok := arguments["destination"].(string); ok && d != "" {
+			query.Destination = strings.ToUpper(d)
+		}
+		if !knownCurrency(query.Currency) && !boardGamePricesCurrencies[query.Currency] {
+			return mcp.NewToolResultError(fmt.Sprintf("No exchange rate for currency '%s'", query.Currency)), nil
+		}
+		includeUsed := true
+		if v, ok := arguments["include_used"].(bool); ok {
+			includeUsed = v
+		}
+		options := []collection.CollectionOption{collection.WithOwned(true)}
+		if v, ok := arguments["include_expansions"].(bool); ok && !v {
+			options = append(options, collection.WithExcludeSubtype("boardgameexpansion"))
+		}
+		top := 10
+		if t, ok := arguments["top"].(float64); ok && t >= 1 {
+			top = int(t)
+		}
+		withItems, _ := arguments["items"].(bool)
+		format := formatArgument(arguments)
+
+		done := traceBGG(ctx, "collection.Query", tracing.Username("bgg.username", username), tracing.Bool("bgg.owned", true))
+		owned, err := collection.Query(username, options...)
+		done(err)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error fetching collection: %v", err)), nil
+		}
+
+		report := valueCollection(ctx, username, owned.Items, query, includeUsed)
+		all := report.Items
+		report.MostValuable = topValued(all, top)
+		if len(report.ByPublisher) > top {
+			report.ByPublisher = report.ByPublisher[:top]
+		}
+		if len(report.ByCategory) > top {
+			report.ByCategory = report.ByCategory[:top]
+		}
+		if !withItems {
+			report.Items = nil
+		}
+
+		return formatToolResult(format, report, tableFor(func() table { return collectionValueTable(report, all) }))
+	}
+
+	return tool, handler
+}
+
+// valueCollection prices the owned items and builds the report. Every game is listed in Items,
+// most valuable first.
+func valueCollection(ctx context.Context, username string, owned []collection.CollectionItem, query PriceQuery, includeUsed bool) *CollectionValueReport {
+	report := &CollectionValueReport{
+		Username:    username,
+		Currency:    query.Currency,
+		Destination: query.Destination,
+		ByPublisher: []ValueGroup{},
+		ByCategory:  []ValueGroup{},
+		Missing:     []ValuedGame{},
+	}
+
+	games := map[int]*ValuedGame{}
+	query.Names = map[int]string{}
+	for _, item := range owned {
+		if g, ok := games[item.ObjectID]; ok {
+			g.Copies++
+			continue
+		}
+		games[item.ObjectID] = &ValuedGame{GameID: item.ObjectID, Name: item.Name, Year: item.YearPublished, Copies: 1}
+		query.IDs = append(query.IDs, item.ObjectID)
+		query.Names[item.ObjectID] = item.Name
+	}
+	if len(query.IDs) == 0 {
+		return report
+	}
+
+	things, err := fetchThings(ctx, query.IDs)
+	if err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("publishers and categories unavailable: %v", err))
+	}
+	for _, item := range things {
+		if g, ok := games[item.ID]; ok {
+			g.Categories = extractEssentialInfo(item).Categories
+			// BGG lists the publishers of every edition; the first is usually the original.
+			for _, link := range item.Links {
+				if link.Type == "boardgamepublisher" {
+					g.Publisher = link.Value
+					break
+				}
+			}
+		}
+	}
+
+	// Price maxPriceGames at a time, so each batch gets the providers' full timeout rather than
+	// a large collection sharing one and timing out as a whole.
+	seen := map[string]bool{}
+	warn := func(w string) {
+		if !seen[w] {
+			seen[w] = true
+			report.Warnings = append(report.Warnings, w)
+		}
+	}
+	ids := query.IDs
+	for start := 0; start < len(ids); start += maxPriceGames {
+		batch := query
+		batch.IDs = ids[start:min(start+maxPriceGames, len(ids))]
+		prices, err := fetchPrices(ctx, batch)
+		if err != nil {
+			warn(fmt.Sprintf("retail prices unavailable: %v", err))
+			continue
+		}
+		for _, w := range prices.Warnings {
+			warn(w)
+		}
+		if prices.RatesDate != "" {
+			report.RatesDate = prices.RatesDate
+		}
+		for _, p := range prices.Games {
+			if g, ok := games[p.GameID]; ok && p.BestOffer != nil {
+				g.Retail, g.Store, g.Link = p.BestOffer.Price, p.BestOffer.Store, p.BestOffer.Link
+			}
+		}
+	}
+
+	if includeUsed {
+		market, unconverted, err := fetchMarketplace(ctx, query.IDs, query.Currency)
+		if err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("used prices unavailable: %v", err))
+		}
+		for _, m := range market {
+			g, ok := games[m.GameID]
+			if !ok || len(m.Listings) == 0 {
+				continue
+			}
+			asks := make([]float64, len(m.Listings))
+			for i, l := range m.Listings {
+				asks[i] = l.Price
+				if !strings.EqualFold(l.OriginalCurrency, query.Currency) {
+					report.RatesDate = fxRatesDate()
+				}
+			}
+			g.Used, g.UsedCount = medianPrice(asks), len(asks)
+		}
+		if len(unconverted) > 0 {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("used listings in %s skipped: no exchange rate", strings.Join(unconverted, ", ")))
+		}
+	}
+
+	publishers := map[string]*ValueGroup{}
+	categories := map[string]*ValueGroup{}
+	addTo := func(groups map[string]*ValueGroup, name string, value float64) {
+		if name == "" {
+			return
+		}
+		g, ok := groups[name]
+		if !ok {
+			g = &ValueGroup{Name: name}
+			groups[name] = g
+		}
+		g.Games++
+		g.Value = round2(g.Value + value)
+	}
+
+	for _, id := range query.IDs {
+		g := games[id]
+		switch {
+		case g.Retail > 0:
+			g.Value, g.Source = round2(g.Retail*float64(g.Copies)), "retail"
+			report.RetailValue = round2(report.RetailValue + g.Value)
+		case g.Used > 0:
+			g.Value, g.Source = round2(g.Used*float64(g.Copies)), "used"
+			report.UsedValue = round2(report.UsedValue + g.Value)
+		default:
+			g.Source = "missing"
+			report.Missing = append(report.Missing, *g)
+		}
+		report.Games++
+		if g.Value > 0 {
+			report.Priced++
+			report.TotalValue = round2(report.TotalValue + g.Value)
+			addTo(publishers, g.Publisher, g.Value)
+			for _, c := range g.Categories {
+				addTo(categories, c, g.Value)
+			}
+		}
+		report.Items = append(report.Items, *g)
+	}
+
+	sort.SliceStable(report.Items, func(i, j int) bool { return report.Items[i].Value > report.Items[j].Value })
+	report.ByPublisher = sortedGroups(publishers)
+	report.ByCategory = sortedGroups(categories)
+	return report
+}
+
+func sortedGroups(groups map[string]*ValueGroup) []ValueGroup {
+	out := make([]ValueGroup, 0, len(groups))
+	for _, g := range groups {
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Value != out[j].Value {
+			return out[i].Value > out[j].Value
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// topValued returns the n most valuable priced games from items, which are sorted by value.
+func topValued(items []ValuedGame, n int) []ValuedGame {
+	out := []ValuedGame{}
+	for _, g := range items {
+		if len(out) == n || g.Value == 0 {
+			break
+		}
+		out = append(out, g)
+	}
+	return out
+}
+
+func medianPrice(prices []float64) float64 {
+	sorted := append([]float64(nil), prices...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return round2((sorted[mid-1] + sorted[mid]) / 2)
+	}
+	return sorted[mid]
+}
+
+// collectionValueTable lists every game followed by a total row, for insurance records.
+func collectionValueTable(report *CollectionValueReport, items []ValuedGame) table {
+	t := table{Headers: []string{"ID", "Name", "Year", "Publisher", "Copies", "Retail", "Used", "Value", "Source", "Currency", "Link"}}
+	copies := 0
+	for _, g := range items {
+		copies += g.Copies
+		year := ""
+		if g.Year > 0 {
+			year = strconv.Itoa(g.Year)
+		}
+		t.Rows = append(t.Rows, []string{
+			strconv.Itoa(g.GameID),
+			g.Name,
+			year,
+			g.Publisher,
+			strconv.Itoa(g.Copies),
+			formatDecimal(g.Retail),
+			formatDecimal(g.Used),
+			formatDecimal(g.Value),
+			g.Source,
+			report.Currency,
+			g.Link,
+		})
+	}
+	t.Rows = append(t.Rows, []string{"", "Total", "", "", strconv.Itoa(copies), "", "", formatDecimal(report.TotalValue), fmt.Sprintf("%d of %d priced", report.Priced, report.Games), report.Currency, ""})
+	return t
+}
@@ -2,10 +2,9 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"os"
 
+	"github.com/kkjdanie/bgg-mcp/tracing"
 	"github.com/kkjdaniel/gogeek/collection"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -67,6 +66,7 @@ func CollectionTool() (mcp.Tool, server.ToolHandlerFunc) {
 		mcp.WithNumber("maxplays",
 			mcp.Description("Filters based on the maximum number of plays of the games in the collection"),
 		),
+		withFormatArgument(FormatJSON, FormatCSV, FormatMarkdown, FormatBGGCSV),
 	)
 
 	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -74,34 +74,28 @@ func CollectionTool() (mcp.Tool, server.ToolHandlerFunc) {
 
 		username, ok := arguments["username"].(string)
 		if !ok || username == "" {
-			return mcp.NewToolResultText("Username is required"), nil
+			return mcp.NewToolResultError("Username is required"), nil
 		}
 
-		if username == "SELF" {
-			envUsername := os.Getenv("BGG_USERNAME")
-			if envUsername == "" {
-				return mcp.NewToolResultText("BGG_USERNAME environment variable not set. Either set it or provide your specific username instead of 'SELF'."), nil
-			}
-			username = envUsername
+		username, err := resolveUsername(ctx, username)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
 		}
 
 		options := buildCollectionOptions(arguments)
 
+		done := traceBGG(ctx, "collection.Query", tracing.Username("bgg.username", username))
 		result, err := collection.Query(username, options...)
+		done(err)
 		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("Error fetching collection: %v", err)), nil
+			return mcp.NewToolResultError(fmt.Sprintf("Error fetching collection: %v", err)), nil
 		}
 
 		if len(result.Items) == 0 {
 			return mcp.NewToolResultText("No items found in collection with the specified filters"), nil
 		}
 
-		out, err := json.Marshal(result.Items)
-		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("Error formatting results: %v", err)), nil
-		}
-
-		return mcp.NewToolResultText(string(out)), nil
+		return formatToolResult(formatArgument(arguments), result.Items, collectionTableFor(result.Items))
 	}
 
 	return tool, handler
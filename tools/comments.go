@@ -0,0 +1,402 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kkjdanie/bgg-mcp/tracing"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ratingCommentsXML is a page of a thing's rating comments from the XML API v2.
+type ratingCommentsXML struct {
+	Items []struct {
+		ID    int `xml:"id,attr"`
+		Names []struct {
+			Type  string `xml:"type,attr"`
+			Value string `xml:"value,attr"`
+		} `xml:"name"`
+		Comments struct {
+			Page       int `xml:"page,attr"`
+			TotalItems int `xml:"totalitems,attr"`
+			Comments   []struct {
+				Username string `xml:"username,attr"`
+				Rating   string `xml:"rating,attr"`
+				Value    string `xml:"value,attr"`
+			} `xml:"comment"`
+		} `xml:"comments"`
+	} `xml:"item"`
+}
+
+type CommentsResult struct {
+	GameID       int             `json:"game_id"`
+	GameName     string          `json:"game_name"`
+	TotalRatings int             `json:"total_ratings"`
+	Scanned      int             `json:"scanned"`
+	Complete     bool            `json:"complete"`
+	Matching     int             `json:"matching"`
+	Stats        RatingStats     `json:"stats"`
+	Languages    map[string]int  `json:"languages,omitempty"`
+	Positive     []RatingComment `json:"positive_samples"`
+	Negative     []RatingComment `json:"negative_samples"`
+}
+
+type RatingStats struct {
+	Mean      float64        `json:"mean"`
+	Median    float64        `json:"median"`
+	StdDev    float64        `json:"std_dev"`
+	Histogram []RatingBucket `json:"histogram"`
+	// Divisiveness is the share of ratings at 4 or below plus the share at 9 or above; a high
+	// value with a middling mean marks a love-it-or-hate-it game.
+	Divisiveness float64 `json:"divisiveness"`
+	// Sample is set when not every rating was read. The statistics and histogram counts then
+	// describe pages spread evenly across all of the game's ratings.
+	Sample bool `json:"sample"`
+}
+
+// RatingBucket counts the ratings that round to Rating.
+type RatingBucket struct {
+	Rating int `json:"rating"`
+	Count  int `json:"count"`
+}
+
+type RatingComment struct {
+	Username string  `json:"username"`
+	Rating   float64 `json:"rating"`
+	Language string  `json:"language,omitempty"`
+	Text     string  `json:"text"`
+}
+
+const (
+	ratingCommentsPageSize = 100
+	commentSampleLength    = 500
+)
+
+func CommentsTool() (mcp.Tool, server.ToolHandlerFunc) {
+	tool := mcp.NewTool("bgg-comments",
+		mcp.WithDescription("Summarise the ratings and comments for a board game on BoardGameGeek (BGG): rating histogram, mean, median, standard deviation and a divisiveness score, plus a sample of representative positive and negative comments. Use this to understand why a game is loved or divisive without reading thousands of comments."),
+		mcp.WithString("name",
+			mcp.Description("The name of the board game"),
+		),
+		mcp.WithNumber("id",
+			mcp.Description("The BoardGameGeek ID of the board game"),
+		),
+		mcp.WithNumber("min_rating",
+			mcp.Description("Only include ratings of at least this value (1-10)"),
+		),
+		mcp.WithNumber("max_rating",
+			mcp.Description("Only include ratings of at most this value (1-10)"),
+		),
+		mcp.WithBoolean("with_text",
+			mcp.Description("Only include ratings that have a written comment"),
+		),
+		mcp.WithString("language",
+			mcp.Enum("en", "de", "fr", "es", "it", "nl"),
+			mcp.Description("Only include comments that look like they are written in this language (a word-based guess)"),
+		),
+		mcp.WithNumber("max_pages",
+			mcp.Description(fmt.Sprintf("Pages of %d ratings to read (default: 5, maximum: 30). Popular games have far more ratings than this; the pages read are then spread evenly over all ratings and the statistics and histogram are marked as a sample.", ratingCommentsPageSize)),
+		),
+		mcp.WithNumber("samples",
+			mcp.Description("Number of positive and of negative comments to return (default: 5)"),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		arguments := request.GetArguments()
+
+		gameID, gameName, err := resolveGame(ctx, arguments)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		maxPages := 5
+		if p, ok := arguments["max_pages"].(float64); ok && p >= 1 {
+			maxPages = min(int(p), 30)
+		}
+		samples := 5
+		if s, ok := arguments["samples"].(float64); ok && s >= 0 {
+			samples = min(int(s), 25)
+		}
+		minRating, maxRating := 0.0, 10.0
+		if r, ok := arguments["min_rating"].(float64); ok {
+			minRating = r
+		}
+		if r, ok := arguments["max_rating"].(float64); ok {
+			maxRating = r
+		}
+		withText, _ := arguments["with_text"].(bool)
+		language, _ := arguments["language"].(string)
+
+		comments, name, total, err := fetchRatingComments(ctx, gameID, maxPages)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if gameName == "" {
+			gameName = name
+		}
+
+		result := CommentsResult{
+			GameID:       gameID,
+			GameName:     gameName,
+			TotalRatings: total,
+			Scanned:      len(comments),
+			Complete:     len(comments) >= total,
+			Languages:    map[string]int{},
+		}
+
+		var matching []RatingComment
+		for _, c := range comments {
+			if c.Rating < minRating || c.Rating > maxRating {
+				continue
+			}
+			if withText && c.Text == "" {
+				continue
+			}
+			if c.Text != "" {
+				c.Language = detectLanguage(c.Text)
+				result.Languages[c.Language]++
+			}
+			if language != "" && c.Language != language {
+				continue
+			}
+			matching = append(matching, c)
+		}
+		result.Matching = len(matching)
+		result.Stats = ratingStats(matching)
+		result.Stats.Sample = !result.Complete
+		result.Positive = sampleComments(matching, func(r float64) bool { return r >= 8 }, samples)
+		result.Negative = sampleComments(matching, func(r float64) bool { return r <= 5 }, samples)
+
+		out, err := json.Marshal(result)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error formatting results: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(out)), nil
+	}
+
+	return tool, handler
+}
+
+// fetchRatingComments reads up to maxPages pages of a game's ratings and returns them with the
+// game's name and the total number of ratings. BGG lists ratings highest first, so when there
+// are more pages than that the pages read are spread evenly across them rather than taken
+// from the top.
+func fetchRatingComments(ctx context.Context, gameID, maxPages int) ([]RatingComment, string, int, error) {
+	var comments []RatingComment
+	name := ""
+	total := 0
+
+	pages := []int{1}
+	for i := 0; i < len(pages); i++ {
+		page := pages[i]
+		url := fmt.Sprintf("https://boardgamegeek.com/xmlapi2/thing?id=%d&ratingcomments=1&page=%d&pagesize=%d", gameID, page, ratingCommentsPageSize)
+
+		done := traceBGG(ctx, "thing.ratingcomments", tracing.Int("bgg.id", gameID), tracing.Int("bgg.page", page))
+		var resp ratingCommentsXML
+		err := fetchXML(ctx, url, &resp)
+		done(err)
+		if err != nil {
+			if i > 0 {
+				// Keep what was read; the result reports how many ratings were scanned.
+				break
+			}
+			return nil, "", 0, fmt.Errorf("error fetching comments: %v", err)
+		}
+		if len(resp.Items) == 0 {
+			return nil, "", 0, fmt.Errorf("game %d was not found", gameID)
+		}
+
+		item := resp.Items[0]
+		if i == 0 {
+			total = item.Comments.TotalItems
+			pages = ratingPages(total, maxPages)
+			for _, n := range item.Names {
+				if n.Type == "primary" {
+					name = n.Value
+				}
+			}
+		}
+		for _, c := range item.Comments.Comments {
+			rating, err := strconv.ParseFloat(c.Rating, 64)
+			if err != nil {
+				continue
+			}
+			comments = append(comments, RatingComment{
+				Username: c.Username,
+				Rating:   rating,
+				Text:     strings.TrimSpace(html.UnescapeString(c.Value)),
+			})
+		}
+		if len(item.Comments.Comments) < ratingCommentsPageSize {
+			break
+		}
+	}
+
+	return comments, name, total, nil
+}
+
+// ratingPages picks at most maxPages of the pages holding total ratings: all of them when they
+// fit, otherwise evenly spaced pages from the first to the last.
+func ratingPages(total, maxPages int) []int {
+	last := max((total+ratingCommentsPageSize-1)/ratingCommentsPageSize, 1)
+	count := min(last, maxPages)
+	pages := make([]int, count)
+	for i := range pages {
+		if last <= maxPages || count == 1 {
+			pages[i] = i + 1
+		} else {
+			pages[i] = 1 + int(math.Round(float64(i*(last-1))/float64(count-1)))
+		}
+	}
+	return pages
+}
+
+func ratingStats(comments []RatingComment) RatingStats {
+	stats := RatingStats{Histogram: make([]RatingBucket, 10)}
+	for i := range stats.Histogram {
+		stats.Histogram[i].Rating = i + 1
+	}
+	if len(comments) == 0 {
+		return stats
+	}
+
+	ratings := make([]float64, len(comments))
+	sum := 0.0
+	low, high := 0, 0
+	for i, c := range comments {
+		ratings[i] = c.Rating
+		sum += c.Rating
+		bucket := min(max(int(math.Round(c.Rating)), 1), 10)
+		stats.Histogram[bucket-1].Count++
+		if c.Rating <= 4 {
+			low++
+		}
+		if c.Rating >= 9 {
+			high++
+		}
+	}
+	sort.Float64s(ratings)
+
+	n := float64(len(ratings))
+	stats.Mean = round2(sum / n)
+	if len(ratings)%2 == 1 {
+		stats.Median = ratings[len(ratings)/2]
+	} else {
+		stats.Median = round2((ratings[len(ratings)/2-1] + ratings[len(ratings)/2]) / 2)
+	}
+	variance := 0.0
+	for _, r := range ratings {
+		variance += (r - sum/n) * (r - sum/n)
+	}
+	stats.StdDev = round2(math.Sqrt(variance / n))
+	stats.Divisiveness = round2(float64(low)/n + float64(high)/n)
+	return stats
+}
+
+// sampleComments picks up to n written comments whose rating matches, preferring ones long
+// enough to explain the rating and spreading the picks across the matching ratings.
+func sampleComments(comments []RatingComment, match func(float64) bool, n int) []RatingComment {
+	var candidates []RatingComment
+	for _, c := range comments {
+		if match(c.Rating) && len(strings.Fields(c.Text)) >= 8 {
+			candidates = append(candidates, c)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return commentScore(candidates[i].Text) > commentScore(candidates[j].Text)
+	})
+
+	// Take the best comment for each distinct rating first, then fill up in score order.
+	var picked []RatingComment
+	used := map[int]bool{}
+	seenRating := map[float64]bool{}
+	for pass := 0; pass < 2 && len(picked) < n; pass++ {
+		for i, c := range candidates {
+			if len(picked) >= n {
+				break
+			}
+			if used[i] || (pass == 0 && seenRating[c.Rating]) {
+				continue
+			}
+			used[i] = true
+			seenRating[c.Rating] = true
+			picked = append(picked, c)
+		}
+	}
+
+	for i := range picked {
+		picked[i].Text = truncateText(picked[i].Text, commentSampleLength)
+	}
+	sort.SliceStable(picked, func(i, j int) bool { return picked[i].Rating > picked[j].Rating })
+	if picked == nil {
+		picked = []RatingComment{}
+	}
+	return picked
+}
+
+// commentScore favours comments of a few sentences over one-liners and walls of text.
+func commentScore(text string) float64 {
+	words := float64(len(strings.Fields(text)))
+	if words > 150 {
+		return 150 - (words-150)/4
+	}
+	return words
+}
+
+var languageStopwords = map[string][]string{
+	"en": {"the", "and", "is", "it", "of", "to", "this", "game", "with", "but", "for", "not"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "mit", "spiel", "auch", "sehr"},
+	"fr": {"le", "la", "les", "et", "est", "un", "une", "jeu", "pas", "des", "pour", "très"},
+	"es": {"el", "la", "los", "y", "es", "un", "una", "juego", "no", "muy", "con", "para"},
+	"it": {"il", "la", "e", "è", "un", "una", "gioco", "non", "molto", "con", "per", "che"},
+	"nl": {"de", "het", "een", "en", "is", "spel", "niet", "van", "met", "maar", "wel", "erg"},
+}
+
+// detectLanguage guesses a comment's language from common words, returning "other" when no
+// language stands out.
+func detectLanguage(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	best, bestScore := "other", 0
+	for lang, stopwords := range languageStopwords {
+		score := 0
+		for _, w := range words {
+			for _, s := range stopwords {
+				if w == s {
+					score++
+					break
+				}
+			}
+		}
+		if score > bestScore || (score == bestScore && score > 0 && lang < best) {
+			best, bestScore = lang, score
+		}
+	}
+	if bestScore < 2 && len(words) > 3 {
+		return "other"
+	}
+	return best
+}
+
+func truncateText(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:n])) + "…"
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/kkjdanie/bgg-mcp/tracing"
 	"github.com/kkjdaniel/gogeek/thing"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -26,6 +27,7 @@ func DetailsTool() (mcp.Tool, server.ToolHandlerFunc) {
 		mcp.WithBoolean("full_details",
 			mcp.Description("Return the complete BGG API response instead of essential info. WARNING: This returns significantly more data and can overload AI context windows. ONLY set this to true if the user explicitly requests 'full details', 'complete data', or similar. Default behavior returns essential info which is sufficient for most use cases."),
 		),
+		withFormatArgument(FormatJSON, FormatCSV, FormatMarkdown),
 	)
 
 	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -37,11 +39,11 @@ func DetailsTool() (mcp.Tool, server.ToolHandlerFunc) {
 		if idsVal, ok := arguments["ids"]; ok && idsVal != nil {
 			idsArray, ok := idsVal.([]interface{})
 			if !ok {
-				return mcp.NewToolResultText("Invalid IDs format - must be an array"), nil
+				return mcp.NewToolResultError("Invalid IDs format - must be an array"), nil
 			}
 			
 			if len(idsArray) > 20 {
-				return mcp.NewToolResultText("Too many IDs provided. Maximum 20 IDs per request."), nil
+				return mcp.NewToolResultError("Too many IDs provided. Maximum 20 IDs per request."), nil
 			}
 			
 			for _, idVal := range idsArray {
@@ -52,10 +54,10 @@ func DetailsTool() (mcp.Tool, server.ToolHandlerFunc) {
 				case string:
 					gameID, err = strconv.Atoi(v)
 					if err != nil {
-						return mcp.NewToolResultText(fmt.Sprintf("Invalid ID format: %s", v)), nil
+						return mcp.NewToolResultError(fmt.Sprintf("Invalid ID format: %s", v)), nil
 					}
 				default:
-					return mcp.NewToolResultText("Invalid ID type in array"), nil
+					return mcp.NewToolResultError("Invalid ID type in array"), nil
 				}
 				gameIDs = append(gameIDs, gameID)
 			}
@@ -67,26 +69,28 @@ func DetailsTool() (mcp.Tool, server.ToolHandlerFunc) {
 			case string:
 				gameID, err = strconv.Atoi(v)
 				if err != nil {
-					return mcp.NewToolResultText("Invalid ID format"), nil
+					return mcp.NewToolResultError("Invalid ID format"), nil
 				}
 			default:
-				return mcp.NewToolResultText("Invalid ID type"), nil
+				return mcp.NewToolResultError("Invalid ID type"), nil
 			}
 			gameIDs = []int{gameID}
 		} else if nameVal, ok := arguments["name"]; ok && nameVal != nil {
 			name := nameVal.(string)
-			bestMatch, err := findBestGameMatch(name)
+			bestMatch, err := findBestGameMatch(ctx, name)
 			if err != nil {
-				return mcp.NewToolResultText(fmt.Sprintf("Failed to find game: %v", err)), nil
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to find game: %v", err)), nil
 			}
 			gameIDs = []int{bestMatch.ID}
 		} else {
-			return mcp.NewToolResultText("Either 'name', 'id', or 'ids' parameter must be provided"), nil
+			return mcp.NewToolResultError("Either 'name', 'id', or 'ids' parameter must be provided"), nil
 		}
 
+		done := traceBGG(ctx, "thing.Query", tracing.Int("bgg.ids", len(gameIDs)))
 		things, err := thing.Query(gameIDs)
+		done(err)
 		if err != nil {
-			return mcp.NewToolResultText(err.Error()), nil
+			return mcp.NewToolResultError(err.Error()), nil
 		}
 
 		if len(things.Items) > 0 {
@@ -95,6 +99,13 @@ func DetailsTool() (mcp.Tool, server.ToolHandlerFunc) {
 				fullDetails = fd
 			}
 
+			if format := formatArgument(arguments); format != FormatJSON && !fullDetails {
+				essentialInfo := extractEssentialInfoList(things.Items)
+				return formatToolResult(format, essentialInfo, tableFor(func() table {
+					return gameInfoTable(essentialInfo)
+				}))
+			}
+
 			var out []byte
 			var err error
 			
@@ -115,7 +126,7 @@ func DetailsTool() (mcp.Tool, server.ToolHandlerFunc) {
 			}
 			
 			if err != nil {
-				return mcp.NewToolResultText(fmt.Sprintf("Error formatting results: %v", err)), nil
+				return mcp.NewToolResultError(fmt.Sprintf("Error formatting results: %v", err)), nil
 			}
 			return mcp.NewToolResultText(string(out)), nil
 		}
@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/kkjdanie/bgg-mcp/tracing"
+	"github.com/kkjdaniel/gogeek/collection"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func FamilyTool() (mcp.Tool, server.ToolHandlerFunc) {
+	tool := mcp.NewTool("bgg-family",
+		mcp.WithDescription("Get a BoardGameGeek (BGG) game family and its member games with years, ranks and ratings. Families group games by series (e.g. 'Series: 18xx'), theme (e.g. 'Theme: Trains'), components or awards (e.g. 'Awards: Spiel des Jahres Winner'). Family IDs are listed in family_ids by bgg-details."),
+		mcp.WithString("name",
+			mcp.Description("The name of the family (e.g., 'Series: 18xx')"),
+		),
+		mcp.WithNumber("id",
+			mcp.Description("The BoardGameGeek family ID (preferred when known, e.g. from family_ids in bgg-details)"),
+		),
+		mcp.WithString("sort",
+			mcp.Enum("year", "rating"),
+			mcp.Description("Sort games newest first ('year', default) or by BGG geek rating ('rating'). BGG lists a family's games newest first, so 'rating' only orders the newest 'limit' games; raise limit to rank more of a large family."),
+		),
+		mcp.WithString("username",
+			mcp.Description("Flag the games this BGG user owns or has played. When the user refers to themselves (me, my, I), use 'SELF' as the value."),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Maximum number of games to list (default: %d)", CurrentSettings().LudographyLimit)),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		arguments := request.GetArguments()
+
+		profile, err := loadProfile(ctx, familyProfile, arguments)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		if sortBy, _ := arguments["sort"].(string); sortBy == "rating" {
+			sort.SliceStable(profile.Ludography, func(i, j int) bool {
+				return profile.Ludography[i].BayesAverage > profile.Ludography[j].BayesAverage
+			})
+			if profile.Truncated {
+				profile.Note = fmt.Sprintf("Sorted by rating among the %d newest of %d games, not the whole family; raise limit to include more.", len(profile.Ludography), profile.TotalGames)
+			}
+		}
+
+		if username, ok := arguments["username"].(string); ok && username != "" {
+			username, err := resolveUsername(ctx, username)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			if err := flagOwnedAndPlayed(ctx, username, profile.Ludography); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+		}
+
+		out, err := json.Marshal(profile)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error formatting results: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(out)), nil
+	}
+
+	return tool, handler
+}
+
+// flagOwnedAndPlayed marks the games username owns or has logged plays of. Logging a play adds
+// the game to the collection, so the whole collection covers both.
+func flagOwnedAndPlayed(ctx context.Context, username string, games []LudographyItem) error {
+	done := traceBGG(ctx, "collection.Query", tracing.Username("bgg.username", username))
+	result, err := collection.Query(username)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("error fetching collection: %v", err)
+	}
+
+	owned := map[int]bool{}
+	plays := map[int]int{}
+	for _, item := range result.Items {
+		if item.Status.Own == 1 {
+			owned[item.ObjectID] = true
+		}
+		plays[item.ObjectID] += item.NumPlays
+	}
+
+	for i := range games {
+		o, p := owned[games[i].ID], plays[games[i].ID] > 0
+		games[i].Owned, games[i].Played = &o, &p
+		games[i].NumPlays = plays[games[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kkjdaniel/gogeek/collection"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	FormatJSON     = "json"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown"
+	FormatBGGCSV   = "bgg_csv"
+	FormatBBCode   = "bbcode"
+	FormatPlain    = "plain"
+)
+
+// table is the tabular form of a tool result used by the CSV and Markdown renderers.
+type table struct {
+	Headers []string
+	Rows    [][]string
+}
+
+func withFormatArgument(formats ...string) mcp.ToolOption {
+	return mcp.WithString("format",
+		mcp.Enum(formats...),
+		mcp.Description(fmt.Sprintf("Output format (default: json). Options: %s. Use csv for spreadsheets and markdown for forum posts.", strings.Join(formats, ", "))),
+	)
+}
+
+func formatArgument(arguments map[string]interface{}) string {
+	if f, ok := arguments["format"].(string); ok && f != "" {
+		return strings.ToLower(f)
+	}
+	return FormatJSON
+}
+
+// renderTable writes t as CSV or a Markdown table.
+func renderTable(format string, t table) (string, error) {
+	switch format {
+	case FormatCSV, FormatBGGCSV:
+		var buf bytes.Buffer
+		cw := csv.NewWriter(&buf)
+		if err := cw.Write(t.Headers); err != nil {
+			return "", err
+		}
+		if err := cw.WriteAll(t.Rows); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	case FormatMarkdown:
+		var sb strings.Builder
+		sb.WriteString("| " + strings.Join(escapeMarkdownCells(t.Headers), " | ") + " |\n")
+		sb.WriteString("|" + strings.Repeat(" --- |", len(t.Headers)) + "\n")
+		for _, row := range t.Rows {
+			sb.WriteString("| " + strings.Join(escapeMarkdownCells(row), " | ") + " |\n")
+		}
+		return sb.String(), nil
+	default:
+		return "", fmt.Errorf("unsupported format '%s'", format)
+	}
+}
+
+func escapeMarkdownCells(cells []string) []string {
+	out := make([]string, len(cells))
+	for i, c := range cells {
+		c = strings.ReplaceAll(c, "|", "\\|")
+		c = strings.ReplaceAll(c, "\r", "")
+		out[i] = strings.ReplaceAll(c, "\n", " ")
+	}
+	return out
+}
+
+// formatToolResult renders v as JSON, or as a table built by toTable for the other formats.
+func formatToolResult(format string, v any, toTable func(format string) (table, error)) (*mcp.CallToolResult, error) {
+	if format == FormatJSON {
+		out, err := json.Marshal(v)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error formatting results: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(out)), nil
+	}
+
+	t, err := toTable(format)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	out, err := renderTable(format, t)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error formatting results: %v", err)), nil
+	}
+	return mcp.NewToolResultText(out), nil
+}
+
+func gameInfoTable(games []EssentialGameInfo) table {
+	t := table{Headers: []string{"id", "name", "year", "type", "players", "play_time", "complexity", "bgg_rating", "bayes_average", "num_ratings", "designer", "publisher"}}
+	for _, g := range games {
+		t.Rows = append(t.Rows, []string{
+			strconv.Itoa(g.ID),
+			g.Name,
+			strconv.Itoa(g.Year),
+			g.Type,
+			g.Players,
+			g.PlayTime,
+			formatDecimal(g.Complexity),
+			formatDecimal(g.BGGRating),
+			formatDecimal(g.BayesAverage),
+			strconv.Itoa(g.NumRatings),
+			g.Designer,
+			g.Publisher,
+		})
+	}
+	return t
+}
+
+func collectionTable(items []collection.CollectionItem) table {
+	t := table{Headers: []string{"id", "name", "year", "subtype", "own", "wishlist", "wishlist_priority", "fortrade", "want", "wanttoplay", "wanttobuy", "preordered", "prevowned", "numplays"}}
+	for _, item := range items {
+		priority := ""
+		if item.Status.Wishlist == 1 {
+			priority = strconv.Itoa(item.Status.WishlistPriority)
+		}
+		t.Rows = append(t.Rows, []string{
+			strconv.Itoa(item.ObjectID),
+			item.Name,
+			strconv.Itoa(item.YearPublished),
+			item.Subtype,
+			yesNo(item.Status.Own),
+			yesNo(item.Status.Wishlist),
+			priority,
+			yesNo(item.Status.ForTrade),
+			yesNo(item.Status.Want),
+			yesNo(item.Status.WantToPlay),
+			yesNo(item.Status.WantToBuy),
+			yesNo(item.Status.Preordered),
+			yesNo(item.Status.PrevOwned),
+			strconv.Itoa(item.NumPlays),
+		})
+	}
+	return t
+}
+
+// bggCollectionTable uses the column names of BGG's own collection CSV export so the
+// file can be re-imported into BGG and other collection managers.
+func bggCollectionTable(items []collection.CollectionItem) table {
+	t := table{Headers: []string{"objectname", "objectid", "numplays", "own", "fortrade", "want", "wanttobuy", "wanttoplay", "prevowned", "preordered", "wishlist", "wishlistpriority", "collid", "objecttype", "yearpublished", "itemtype"}}
+	for _, item := range items {
+		objectType := item.ObjectType
+		if objectType == "" {
+			objectType = "thing"
+		}
+		itemType := "standalone"
+		if item.Subtype == "boardgameexpansion" {
+			itemType = "expansion"
+		}
+		t.Rows = append(t.Rows, []string{
+			item.Name,
+			strconv.Itoa(item.ObjectID),
+			strconv.Itoa(item.NumPlays),
+			strconv.Itoa(item.Status.Own),
+			strconv.Itoa(item.Status.ForTrade),
+			strconv.Itoa(item.Status.Want),
+			strconv.Itoa(item.Status.WantToBuy),
+			strconv.Itoa(item.Status.WantToPlay),
+			strconv.Itoa(item.Status.PrevOwned),
+			strconv.Itoa(item.Status.Preordered),
+			strconv.Itoa(item.Status.Wishlist),
+			strconv.Itoa(item.Status.WishlistPriority),
+			strconv.Itoa(item.CollID),
+			objectType,
+			strconv.Itoa(item.YearPublished),
+			itemType,
+		})
+	}
+	return t
+}
+
+func tradeTable(trade TradeOpportunity) table {
+	t := table{Headers: []string{"game_id", "name", "year", "owner", "wanted_by", "for_trade"}}
+	for _, item := range trade.User1HasWanted {
+		t.Rows = append(t.Rows, []string{
+			strconv.Itoa(item.GameID),
+			item.Name,
+			strconv.Itoa(item.YearPublished),
+			trade.User1Username,
+			trade.User2Username,
+			strconv.FormatBool(item.ForTrade),
+		})
+	}
+	return t
+}
+
+func yesNo(flag int) string {
+	if flag == 1 {
+		return "yes"
+	}
+	return "no"
+}
+
+func formatDecimal(v float64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// negotiateFormat picks the REST response format from an explicit ?format= parameter,
+// falling back to the Accept header. Unknown media types fall back to JSON.
+func negotiateFormat(r *http.Request) string {
+	if f := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format"))); f != "" {
+		switch f {
+		case FormatCSV, FormatMarkdown, FormatBGGCSV:
+			return f
+		case "md":
+			return FormatMarkdown
+		}
+		return FormatJSON
+	}
+
+	type candidate struct {
+		format string
+		q      float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if qs, ok := params["q"]; ok {
+			if v, err := strconv.ParseFloat(qs, 64); err == nil {
+				q = v
+			}
+		}
+		format := ""
+		switch mediaType {
+		case "application/json":
+			format = FormatJSON
+		case "text/csv":
+			format = FormatCSV
+			if params["profile"] == "bgg" {
+				format = FormatBGGCSV
+			}
+		case "text/markdown":
+			format = FormatMarkdown
+		}
+		if format != "" && q > 0 {
+			candidates = append(candidates, candidate{format, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return FormatJSON
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].format
+}
+
+// writeNegotiated writes v as JSON or, when the client asked for CSV or Markdown, as the
+// table produced by toTable. A nil toTable means the route only supports JSON.
+func writeNegotiated(w http.ResponseWriter, r *http.Request, v any, toTable func(format string) (table, error)) {
+	// The body depends on Accept whichever format is picked, so caches must key on it.
+	w.Header().Add("Vary", "Accept")
+
+	format := negotiateFormat(r)
+	if format == FormatJSON || toTable == nil {
+		writeJSON(w, v)
+		return
+	}
+	t, err := toTable(format)
+	if err != nil {
+		writeJSONStatus(w, http.StatusNotAcceptable, map[string]string{"error": err.Error()})
+		return
+	}
+	out, err := renderTable(format, t)
+	if err != nil {
+		writeJSONStatus(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	contentType := "text/csv; charset=utf-8"
+	if format == FormatMarkdown {
+		contentType = "text/markdown; charset=utf-8"
+	}
+	setAPIHeaders(w, contentType)
+	_, _ = w.Write([]byte(out))
+}
+
+// tableFor returns a table builder that rejects the BGG collection CSV format, which
+// only makes sense for collection results.
+func tableFor(build func() table) func(format string) (table, error) {
+	return func(format string) (table, error) {
+		if format == FormatBGGCSV {
+			return table{}, fmt.Errorf("format '%s' is only available for collections", FormatBGGCSV)
+		}
+		return build(), nil
+	}
+}
+
+func collectionTableFor(items []collection.CollectionItem) func(format string) (table, error) {
+	return func(format string) (table, error) {
+		if format == FormatBGGCSV {
+			return bggCollectionTable(items), nil
+		}
+		return collectionTable(items), nil
+	}
+}
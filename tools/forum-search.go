@@ -0,0 +1,287 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kkjdanie/bgg-mcp/tracing"
+	"github.com/kkjdaniel/gogeek/forum"
+	"github.com/kkjdaniel/gogeek/forumlist"
+	"github.com/kkjdaniel/gogeek/thread"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type ForumSearchResult struct {
+	GameName       string             `json:"game_name,omitempty"`
+	GameID         int                `json:"game_id"`
+	Query          string             `json:"query"`
+	Forums         []ForumSearched    `json:"forums"`
+	ThreadsScanned int                `json:"threads_scanned"`
+	BodiesIndexed  int                `json:"bodies_indexed"`
+	Matches        []ForumThreadMatch `json:"matches"`
+}
+
+type ForumSearched struct {
+	ID             int    `json:"id"`
+	Title          string `json:"title"`
+	TotalThreads   int    `json:"total_threads"`
+	ThreadsScanned int    `json:"threads_scanned"`
+}
+
+type ForumThreadMatch struct {
+	ThreadID     int     `json:"thread_id"`
+	Subject      string  `json:"subject"`
+	Forum        string  `json:"forum"`
+	Replies      int     `json:"replies"`
+	LastPostDate string  `json:"last_post_date,omitempty"`
+	Score        float64 `json:"score"`
+	Snippet      string  `json:"snippet,omitempty"`
+	Link         string  `json:"link"`
+}
+
+// forumThreadCandidate is a thread found while scanning forums, with its first post once fetched.
+type forumThreadCandidate struct {
+	Thread    forum.Thread
+	Forum     string
+	FirstPost string
+}
+
+func ForumSearchTool() (mcp.Tool, server.ToolHandlerFunc) {
+	tool := mcp.NewTool("bgg-forum-search",
+		mcp.WithDescription("Search all of a board game's BoardGameGeek (BGG) forums (Rules, Strategy, Variants, Reviews, Sessions, etc.) for threads relevant to a question or topic. Returns the best matching threads with snippets; use bgg-thread-details to read a thread in full."),
+		mcp.WithString("name",
+			mcp.Description("The name of the board game"),
+		),
+		mcp.WithNumber("id",
+			mcp.Description("The BoardGameGeek ID of the board game"),
+		),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("Keywords or question to search the forums for (e.g., 'solo variant', 'best opening strategy')"),
+		),
+		mcp.WithString("forums",
+			mcp.Description("Comma-separated forum titles to restrict the search to (e.g., 'Strategy,Variants'). Default: all forums"),
+		),
+		mcp.WithBoolean("include_bodies",
+			mcp.Description("Also fetch and index the first post of the most promising threads for better ranking and snippets (slower, default: false)"),
+		),
+		mcp.WithNumber("max_pages",
+			mcp.Description(fmt.Sprintf("Maximum pages of 50 threads to scan per forum (default: %d)", CurrentSettings().ForumSearchMaxPages)),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of matching threads to return (default: 10)"),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		arguments := request.GetArguments()
+
+		query, ok := arguments["query"].(string)
+		if !ok || strings.TrimSpace(query) == "" {
+			return mcp.NewToolResultError("query parameter is required"), nil
+		}
+
+		gameID, gameName, err := resolveGame(ctx, arguments)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		maxPages := CurrentSettings().ForumSearchMaxPages
+		if mp, ok := arguments["max_pages"].(float64); ok && mp > 0 {
+			maxPages = int(mp)
+		}
+
+		limit := 10
+		if l, ok := arguments["limit"].(float64); ok && l > 0 {
+			limit = int(l)
+		}
+
+		var forumFilter []string
+		if f, ok := arguments["forums"].(string); ok && f != "" {
+			for _, title := range strings.Split(f, ",") {
+				if title = strings.ToLower(strings.TrimSpace(title)); title != "" {
+					forumFilter = append(forumFilter, title)
+				}
+			}
+		}
+
+		includeBodies, _ := arguments["include_bodies"].(bool)
+
+		result, err := searchGameForums(ctx, gameID, query, forumFilter, maxPages, limit, includeBodies)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		result.GameName = gameName
+
+		out, err := json.Marshal(result)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error formatting results: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(out)), nil
+	}
+
+	return tool, handler
+}
+
+// searchGameForums scans the threads of every forum for a game and ranks them against query
+// with BM25 over thread subjects and, when includeBodies is set, the first post of the best
+// subject matches.
+func searchGameForums(ctx context.Context, gameID int, query string, forumFilter []string, maxPages, limit int, includeBodies bool) (*ForumSearchResult, error) {
+	queryTerms := tokenize(query)
+	if len(queryTerms) == 0 {
+		return nil, fmt.Errorf("query must contain at least one keyword")
+	}
+
+	candidates, searched, err := collectForumThreads(ctx, gameID, forumFilter, maxPages)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ForumSearchResult{
+		GameID:         gameID,
+		Query:          query,
+		Forums:         searched,
+		ThreadsScanned: len(candidates),
+		Matches:        []ForumThreadMatch{},
+	}
+	if len(candidates) == 0 {
+		return result, nil
+	}
+
+	ranked := rankForumThreads(candidates, queryTerms)
+
+	if includeBodies {
+		// Fetch first posts for the strongest subject matches, topping up with the most
+		// recently active threads when few subjects match.
+		bodyBudget := limit * 2
+		fetch := make([]int, 0, bodyBudget)
+		seen := map[int]bool{}
+		for _, r := range ranked {
+			if len(fetch) >= bodyBudget {
+				break
+			}
+			fetch = append(fetch, r.Index)
+			seen[r.Index] = true
+		}
+		for i := 0; i < len(candidates) && len(fetch) < bodyBudget; i++ {
+			if !seen[i] {
+				fetch = append(fetch, i)
+			}
+		}
+
+		for _, i := range fetch {
+			done := traceBGG(ctx, "thread.Query", tracing.Int("bgg.thread_id", candidates[i].Thread.ID))
+			td, err := thread.Query(candidates[i].Thread.ID)
+			done(err)
+			if err != nil || len(td.Articles) == 0 {
+				continue
+			}
+			candidates[i].FirstPost = sanitizeDescription(stripHTML(td.Articles[0].Body))
+			result.BodiesIndexed++
+		}
+
+		ranked = rankForumThreads(candidates, queryTerms)
+	}
+
+	for _, r := range ranked {
+		if len(result.Matches) >= limit {
+			break
+		}
+		c := candidates[r.Index]
+		snippet := ""
+		if c.FirstPost != "" {
+			snippet = snippetAround(c.FirstPost, queryTerms, 240)
+		}
+		result.Matches = append(result.Matches, ForumThreadMatch{
+			ThreadID:     c.Thread.ID,
+			Subject:      c.Thread.Subject,
+			Forum:        c.Forum,
+			Replies:      c.Thread.NumArticles - 1,
+			LastPostDate: c.Thread.LastPostDate,
+			Score:        roundScore(r.Score),
+			Snippet:      snippet,
+			Link:         fmt.Sprintf("https://boardgamegeek.com/thread/%d", c.Thread.ID),
+		})
+	}
+
+	return result, nil
+}
+
+func collectForumThreads(ctx context.Context, gameID int, forumFilter []string, maxPages int) ([]forumThreadCandidate, []ForumSearched, error) {
+	done := traceBGG(ctx, "forumlist.Query", tracing.Int("bgg.id", gameID))
+	forums, err := forumlist.Query(gameID, forumlist.Thing)
+	done(err)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get forum list: %v", err)
+	}
+
+	var candidates []forumThreadCandidate
+	searched := []ForumSearched{}
+
+	for _, f := range forums.Forums {
+		if !matchesForumFilter(f.Title, forumFilter) || f.NumThreads == 0 {
+			continue
+		}
+
+		info := ForumSearched{ID: f.ID, Title: f.Title, TotalThreads: f.NumThreads}
+		for page := 1; page <= maxPages; page++ {
+			done := traceBGG(ctx, "forum.Query", tracing.Int("bgg.forum_id", f.ID), tracing.Int("bgg.page", page))
+			forumData, err := forum.Query(f.ID, forum.WithPage(page))
+			done(err)
+			if err != nil {
+				if page == 1 {
+					return nil, nil, fmt.Errorf("failed to get threads for forum '%s': %v", f.Title, err)
+				}
+				break
+			}
+			for _, th := range forumData.Threads {
+				candidates = append(candidates, forumThreadCandidate{Thread: th, Forum: f.Title})
+			}
+			info.ThreadsScanned += len(forumData.Threads)
+
+			// If we got less than 50 threads, we've reached the last page
+			if len(forumData.Threads) < 50 {
+				break
+			}
+		}
+		searched = append(searched, info)
+	}
+
+	return candidates, searched, nil
+}
+
+func matchesForumFilter(title string, filter []string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	titleLower := strings.ToLower(title)
+	for _, f := range filter {
+		if strings.Contains(titleLower, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// rankForumThreads scores candidates with the subject counted three times so a subject match
+// outweighs a passing mention in a long first post.
+func rankForumThreads(candidates []forumThreadCandidate, queryTerms []string) []scoredDoc {
+	docs := make([][]string, len(candidates))
+	for i, c := range candidates {
+		subject := tokenize(c.Thread.Subject)
+		doc := make([]string, 0, len(subject)*3)
+		for n := 0; n < 3; n++ {
+			doc = append(doc, subject...)
+		}
+		docs[i] = append(doc, tokenize(c.FirstPost)...)
+	}
+	return newBM25Index(docs).Rank(queryTerms)
+}
+
+func roundScore(score float64) float64 {
+	return float64(int(score*100+0.5)) / 100
+}
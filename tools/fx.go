@@ -0,0 +1,215 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FXConfig sets where exchange rates come from. Both sources use the same JSON shape, e.g.
+// {"base": "EUR", "date": "2025-06-02", "rates": {"USD": 1.14, "GBP": 0.84}}, which is also
+// what Frankfurter-style rate APIs return. Rates are units of each currency per unit of base.
+type FXConfig struct {
+	// File is a local rate table, read at startup and on every refresh.
+	File string
+	// ProviderURL is an optional rate API; when it answers, its rates replace the file's.
+	ProviderURL string
+	// Refresh is how often the rates are reloaded; zero means daily.
+	Refresh time.Duration
+}
+
+// fxTable is a set of exchange rates against one base currency.
+type fxTable struct {
+	Base  string             `json:"base"`
+	Date  string             `json:"date"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// builtinFXTable holds approximate rates used until a file or provider is configured, enough
+// to compare prices listed in different currencies.
+var builtinFXTable = fxTable{
+	Base: "USD",
+	Date: "2025-01-02",
+	Rates: map[string]float64{
+		"EUR": 0.966,
+		"GBP": 0.803,
+		"CAD": 1.44,
+		"AUD": 1.61,
+		"NZD": 1.78,
+		"CHF": 0.907,
+		"SEK": 11.05,
+		"DKK": 7.20,
+		"NOK": 11.36,
+		"PLN": 4.13,
+		"CZK": 24.35,
+		"JPY": 157.2,
+	},
+}
+
+var (
+	fxMu     sync.RWMutex
+	fxRates  = builtinFXTable
+	fxCancel context.CancelFunc
+)
+
+// ConfigureFX loads exchange rates from cfg and keeps them refreshed in the background. The
+// file must load at startup; later refresh failures keep the last good rates.
+func ConfigureFX(cfg FXConfig) error {
+	if cfg.Refresh <= 0 {
+		cfg.Refresh = 24 * time.Hour
+	}
+
+	fxMu.Lock()
+	if fxCancel != nil {
+		fxCancel()
+		fxCancel = nil
+	}
+	fxRates = builtinFXTable
+	fxMu.Unlock()
+
+	if cfg.File != "" {
+		table, err := readFXFile(cfg.File)
+		if err != nil {
+			return err
+		}
+		setFXTable(table)
+	}
+	if cfg.File == "" && cfg.ProviderURL == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fxMu.Lock()
+	fxCancel = cancel
+	fxMu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(cfg.Refresh)
+		defer ticker.Stop()
+		for {
+			refreshFX(ctx, cfg)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+func refreshFX(ctx context.Context, cfg FXConfig) {
+	if cfg.File != "" {
+		table, err := readFXFile(cfg.File)
+		if err != nil {
+			log.Printf("Error reloading exchange rates: %v", err)
+		} else {
+			setFXTable(table)
+		}
+	}
+	if cfg.ProviderURL != "" {
+		var table fxTable
+		err := fetchJSON(ctx, cfg.ProviderURL, &table)
+		if err == nil {
+			err = table.validate()
+		}
+		if err != nil {
+			log.Printf("Error fetching exchange rates: %v", err)
+			return
+		}
+		setFXTable(table)
+	}
+}
+
+func readFXFile(path string) (fxTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fxTable{}, fmt.Errorf("reading exchange rate file: %w", err)
+	}
+	var table fxTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return fxTable{}, fmt.Errorf("parsing exchange rate file %s: %w", path, err)
+	}
+	if err := table.validate(); err != nil {
+		return fxTable{}, fmt.Errorf("exchange rate file %s: %w", path, err)
+	}
+	return table, nil
+}
+
+func (t *fxTable) validate() error {
+	t.Base = strings.ToUpper(t.Base)
+	if len(t.Base) != 3 {
+		return fmt.Errorf("base %q must be a three-letter currency code", t.Base)
+	}
+	if len(t.Rates) == 0 {
+		return fmt.Errorf("no rates")
+	}
+	rates := make(map[string]float64, len(t.Rates))
+	for code, rate := range t.Rates {
+		if rate <= 0 {
+			return fmt.Errorf("rate for %s must be positive", code)
+		}
+		rates[strings.ToUpper(code)] = rate
+	}
+	t.Rates = rates
+	return nil
+}
+
+func setFXTable(table fxTable) {
+	fxMu.Lock()
+	fxRates = table
+	fxMu.Unlock()
+}
+
+// fxRatesDate returns the date of the exchange rates in use.
+func fxRatesDate() string {
+	fxMu.RLock()
+	defer fxMu.RUnlock()
+	return fxRates.Date
+}
+
+// rate returns the units of code per unit of the table's base currency.
+func (t fxTable) rate(code string) (float64, bool) {
+	if code == t.Base {
+		return 1, true
+	}
+	rate, ok := t.Rates[code]
+	return rate, ok
+}
+
+// knownCurrency reports whether prices can be converted to or from code.
+func knownCurrency(code string) bool {
+	fxMu.RLock()
+	defer fxMu.RUnlock()
+	_, ok := fxRates.rate(strings.ToUpper(code))
+	return ok
+}
+
+// convertCurrency converts amount from one currency to another using the current rates,
+// rounded to cents.
+func convertCurrency(amount float64, from, to string) (float64, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return amount, nil
+	}
+
+	fxMu.RLock()
+	table := fxRates
+	fxMu.RUnlock()
+
+	fromRate, ok := table.rate(from)
+	if !ok {
+		return 0, fmt.Errorf("no exchange rate for %s", from)
+	}
+	toRate, ok := table.rate(to)
+	if !ok {
+		return 0, fmt.Errorf("no exchange rate for %s", to)
+	}
+	return math.Round(amount/fromRate*toRate*100) / 100, nil
+}
@@ -0,0 +1,414 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kkjdanie/bgg-mcp/tracing"
+	"github.com/kkjdaniel/gogeek/collection"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// geeklistXML is the XML API v1 geeklist response; gogeek has no geeklist support.
+type geeklistXML struct {
+	ID          int                  `xml:"id,attr"`
+	PostDate    string               `xml:"postdate"`
+	EditDate    string               `xml:"editdate"`
+	Thumbs      int                  `xml:"thumbs"`
+	NumItems    int                  `xml:"numitems"`
+	Username    string               `xml:"username"`
+	Title       string               `xml:"title"`
+	Description string               `xml:"description"`
+	Comments    []geeklistCommentXML `xml:"comment"`
+	Items       []geeklistItemXML    `xml:"item"`
+}
+
+type geeklistItemXML struct {
+	ID         int                  `xml:"id,attr"`
+	ObjectType string               `xml:"objecttype,attr"`
+	Subtype    string               `xml:"subtype,attr"`
+	ObjectID   int                  `xml:"objectid,attr"`
+	ObjectName string               `xml:"objectname,attr"`
+	Username   string               `xml:"username,attr"`
+	PostDate   string               `xml:"postdate,attr"`
+	Thumbs     int                  `xml:"thumbs,attr"`
+	Body       string               `xml:"body"`
+	Comments   []geeklistCommentXML `xml:"comment"`
+}
+
+type geeklistCommentXML struct {
+	Username string `xml:"username,attr"`
+	PostDate string `xml:"postdate,attr"`
+	Thumbs   int    `xml:"thumbs,attr"`
+	Text     string `xml:",chardata"`
+}
+
+type GeekList struct {
+	ID          int               `json:"id"`
+	Title       string            `json:"title"`
+	Author      string            `json:"author"`
+	PostDate    string            `json:"post_date"`
+	EditDate    string            `json:"edit_date,omitempty"`
+	Thumbs      int               `json:"thumbs"`
+	Description string            `json:"description,omitempty"`
+	URL         string            `json:"url"`
+	Summary     GeekListSummary   `json:"summary"`
+	Comments    []GeekListComment `json:"comments,omitempty"`
+	Page        int               `json:"page"`
+	TotalPages  int               `json:"total_pages"`
+	Items       []GeekListItem    `json:"items"`
+}
+
+type GeekListSummary struct {
+	TotalItems    int      `json:"total_items"`
+	MatchingItems int      `json:"matching_items"`
+	ItemThumbs    int      `json:"item_thumbs"`
+	MostThumbed   []string `json:"most_thumbed,omitempty"`
+	Owned         *int     `json:"owned,omitempty"`
+	Wishlisted    *int     `json:"wishlisted,omitempty"`
+}
+
+type GeekListItem struct {
+	Position   int                `json:"position"`
+	ID         int                `json:"id"`
+	ObjectType string             `json:"object_type"`
+	Subtype    string             `json:"subtype"`
+	ObjectID   int                `json:"object_id"`
+	Name       string             `json:"name"`
+	AddedBy    string             `json:"added_by"`
+	PostDate   string             `json:"post_date"`
+	Thumbs     int                `json:"thumbs"`
+	Body       string             `json:"body,omitempty"`
+	Comments   []GeekListComment  `json:"comments,omitempty"`
+	Game       *EssentialGameInfo `json:"game,omitempty"`
+	Owned      *bool              `json:"owned,omitempty"`
+	Wishlisted *bool              `json:"wishlisted,omitempty"`
+}
+
+type GeekListComment struct {
+	Username string `json:"username"`
+	PostDate string `json:"post_date"`
+	Thumbs   int    `json:"thumbs"`
+	Text     string `json:"text"`
+}
+
+const geeklistPageSize = 25
+
+func GeekListTool() (mcp.Tool, server.ToolHandlerFunc) {
+	tool := mcp.NewTool("bgg-geeklist",
+		mcp.WithDescription("Read a BoardGameGeek (BGG) geeklist by ID, such as a 'Top 100 solo games' list or a convention preview, with its items, comments and thumbs. Items are returned a page at a time; board games include their essential details. Can search the list and flag which items a user owns or wishlists."),
+		mcp.WithNumber("id",
+			mcp.Required(),
+			mcp.Description("The geeklist ID, from a URL like boardgamegeek.com/geeklist/12345"),
+		),
+		mcp.WithString("query",
+			mcp.Description("Only return items whose name, description or comments contain this text"),
+		),
+		mcp.WithNumber("page",
+			mcp.Description("Page of items to return (default: 1)"),
+		),
+		mcp.WithNumber("page_size",
+			mcp.Description(fmt.Sprintf("Items per page (default: %d, maximum: 100)", geeklistPageSize)),
+		),
+		mcp.WithBoolean("comments",
+			mcp.Description("Include list and item comments (default: true)"),
+		),
+		mcp.WithBoolean("hydrate",
+			mcp.Description("Include essential game details for board game items (default: true)"),
+		),
+		mcp.WithString("username",
+			mcp.Description("Flag items this BGG user owns or has wishlisted. When the user refers to themselves (me, my, I), use 'SELF' as the value."),
+		),
+		withFormatArgument(FormatJSON, FormatCSV, FormatMarkdown),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		arguments := request.GetArguments()
+
+		id, ok := arguments["id"].(float64)
+		if !ok || id <= 0 {
+			return mcp.NewToolResultError("A geeklist ID is required"), nil
+		}
+
+		page := 1
+		if p, ok := arguments["page"].(float64); ok && p >= 1 {
+			page = int(p)
+		}
+		pageSize := geeklistPageSize
+		if s, ok := arguments["page_size"].(float64); ok && s >= 1 {
+			pageSize = min(int(s), 100)
+		}
+		includeComments := true
+		if c, ok := arguments["comments"].(bool); ok {
+			includeComments = c
+		}
+		hydrate := true
+		if h, ok := arguments["hydrate"].(bool); ok {
+			hydrate = h
+		}
+
+		raw, err := fetchGeekList(ctx, int(id), includeComments)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		list := GeekList{
+			ID:          raw.ID,
+			Title:       html.UnescapeString(raw.Title),
+			Author:      raw.Username,
+			PostDate:    raw.PostDate,
+			EditDate:    raw.EditDate,
+			Thumbs:      raw.Thumbs,
+			Description: strings.TrimSpace(html.UnescapeString(raw.Description)),
+			URL:         fmt.Sprintf("https://boardgamegeek.com/geeklist/%d", raw.ID),
+			Page:        page,
+		}
+		if includeComments {
+			list.Comments = geekListComments(raw.Comments)
+		}
+
+		query := ""
+		if q, ok := arguments["query"].(string); ok {
+			query = strings.ToLower(strings.TrimSpace(q))
+		}
+
+		var items []GeekListItem
+		for i, it := range raw.Items {
+			item := GeekListItem{
+				Position:   i + 1,
+				ID:         it.ID,
+				ObjectType: it.ObjectType,
+				Subtype:    it.Subtype,
+				ObjectID:   it.ObjectID,
+				Name:       html.UnescapeString(it.ObjectName),
+				AddedBy:    it.Username,
+				PostDate:   it.PostDate,
+				Thumbs:     it.Thumbs,
+				Body:       strings.TrimSpace(html.UnescapeString(it.Body)),
+			}
+			if includeComments {
+				item.Comments = geekListComments(it.Comments)
+			}
+			if query != "" && !geekListItemMatches(item, query) {
+				continue
+			}
+			list.Summary.ItemThumbs += item.Thumbs
+			items = append(items, item)
+		}
+		list.Summary.TotalItems = len(raw.Items)
+		list.Summary.MatchingItems = len(items)
+		list.Summary.MostThumbed = mostThumbed(items, 5)
+
+		if username, ok := arguments["username"].(string); ok && username != "" {
+			username, err := resolveUsername(ctx, username)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			if err := flagCollectionItems(ctx, username, items, &list.Summary); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+		}
+
+		list.TotalPages = (len(items) + pageSize - 1) / pageSize
+		start := (page - 1) * pageSize
+		if start < len(items) {
+			list.Items = items[start:min(start+pageSize, len(items))]
+		}
+
+		if hydrate {
+			hydrateGeekListItems(ctx, list.Items)
+		}
+
+		return formatToolResult(formatArgument(arguments), list, tableFor(func() table {
+			return geekListTable(list.Items)
+		}))
+	}
+
+	return tool, handler
+}
+
+// fetchGeekList reads a geeklist, retrying while BGG prepares it: the v1 API answers a list
+// it has not cached recently with 202 Accepted.
+func fetchGeekList(ctx context.Context, id int, comments bool) (*geeklistXML, error) {
+	url := fmt.Sprintf("https://boardgamegeek.com/xmlapi/geeklist/%d", id)
+	if comments {
+		url += "?comments=1"
+	}
+
+	done := traceBGG(ctx, "geeklist", tracing.Int("bgg.geeklist_id", id))
+	var list geeklistXML
+	var err error
+	for attempt := 0; attempt < 4; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				done(ctx.Err())
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(attempt) * 2 * time.Second):
+			}
+		}
+		if err = fetchXML(ctx, url, &list); err != errBGGQueued {
+			break
+		}
+	}
+	done(err)
+	if err == errBGGQueued {
+		return nil, fmt.Errorf("BGG is still preparing geeklist %d; try again in a few seconds", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching geeklist: %v", err)
+	}
+	if list.ID == 0 && len(list.Items) == 0 {
+		return nil, fmt.Errorf("geeklist %d was not found", id)
+	}
+	return &list, nil
+}
+
+func geekListComments(raw []geeklistCommentXML) []GeekListComment {
+	var comments []GeekListComment
+	for _, c := range raw {
+		comments = append(comments, GeekListComment{
+			Username: c.Username,
+			PostDate: c.PostDate,
+			Thumbs:   c.Thumbs,
+			Text:     strings.TrimSpace(html.UnescapeString(c.Text)),
+		})
+	}
+	return comments
+}
+
+func geekListItemMatches(item GeekListItem, query string) bool {
+	if strings.Contains(strings.ToLower(item.Name), query) || strings.Contains(strings.ToLower(item.Body), query) {
+		return true
+	}
+	for _, c := range item.Comments {
+		if strings.Contains(strings.ToLower(c.Text), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// mostThumbed returns the names of the n items with the most thumbs.
+func mostThumbed(items []GeekListItem, n int) []string {
+	sorted := make([]GeekListItem, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Thumbs > sorted[j].Thumbs })
+
+	var names []string
+	for _, item := range sorted[:min(n, len(sorted))] {
+		if item.Thumbs > 0 {
+			names = append(names, fmt.Sprintf("%s (%d thumbs)", item.Name, item.Thumbs))
+		}
+	}
+	return names
+}
+
+// flagCollectionItems marks the items username owns or has wishlisted and counts them.
+func flagCollectionItems(ctx context.Context, username string, items []GeekListItem, summary *GeekListSummary) error {
+	done := traceBGG(ctx, "collection.Query", tracing.Username("bgg.username", username))
+	result, err := collection.Query(username)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("error fetching collection: %v", err)
+	}
+
+	owned := map[int]bool{}
+	wishlisted := map[int]bool{}
+	for _, c := range result.Items {
+		if c.Status.Own == 1 {
+			owned[c.ObjectID] = true
+		}
+		if c.Status.Wishlist == 1 {
+			wishlisted[c.ObjectID] = true
+		}
+	}
+
+	ownedCount, wishlistedCount := 0, 0
+	for i := range items {
+		if items[i].ObjectType != "thing" {
+			continue
+		}
+		o, w := owned[items[i].ObjectID], wishlisted[items[i].ObjectID]
+		items[i].Owned, items[i].Wishlisted = &o, &w
+		if o {
+			ownedCount++
+		}
+		if w {
+			wishlistedCount++
+		}
+	}
+	summary.Owned, summary.Wishlisted = &ownedCount, &wishlistedCount
+	return nil
+}
+
+// hydrateGeekListItems adds essential game details to the board game items. A failure only
+// leaves the details out.
+func hydrateGeekListItems(ctx context.Context, items []GeekListItem) {
+	var ids []int
+	for _, item := range items {
+		if item.ObjectType == "thing" && strings.HasPrefix(item.Subtype, "boardgame") {
+			ids = append(ids, item.ObjectID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	things, err := fetchThings(ctx, ids)
+	if err != nil {
+		return
+	}
+	games := map[int]EssentialGameInfo{}
+	for _, thing := range things {
+		if len(thing.Name) > 0 {
+			games[thing.ID] = extractEssentialInfo(thing)
+		}
+	}
+	for i := range items {
+		if game, ok := games[items[i].ObjectID]; ok && items[i].ObjectType == "thing" {
+			game.Description = ""
+			items[i].Game = &game
+		}
+	}
+}
+
+func geekListTable(items []GeekListItem) table {
+	t := table{Headers: []string{"position", "object_id", "name", "subtype", "added_by", "thumbs", "year", "bgg_rating", "owned", "wishlisted"}}
+	for _, item := range items {
+		year, rating := "", ""
+		if item.Game != nil {
+			year = strconv.Itoa(item.Game.Year)
+			rating = formatDecimal(item.Game.BGGRating)
+		}
+		t.Rows = append(t.Rows, []string{
+			strconv.Itoa(item.Position),
+			strconv.Itoa(item.ObjectID),
+			item.Name,
+			item.Subtype,
+			item.AddedBy,
+			strconv.Itoa(item.Thumbs),
+			year,
+			rating,
+			optionalYesNo(item.Owned),
+			optionalYesNo(item.Wishlisted),
+		})
+	}
+	return t
+}
+
+func optionalYesNo(flag *bool) string {
+	if flag == nil {
+		return ""
+	}
+	if *flag {
+		return yesNo(1)
+	}
+	return yesNo(0)
+}
@@ -0,0 +1,415 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kkjdanie/bgg-mcp/tracing"
+	"github.com/kkjdaniel/gogeek/collection"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// guildXML is the XML API v2 guild response; gogeek has no guild support.
+type guildXML struct {
+	ID          int    `xml:"id,attr"`
+	Name        string `xml:"name,attr"`
+	Created     string `xml:"created,attr"`
+	Category    string `xml:"category"`
+	Website     string `xml:"website"`
+	Manager     string `xml:"manager"`
+	Description string `xml:"description"`
+	Location    struct {
+		City          string `xml:"city"`
+		StateOrRegion string `xml:"stateorregion"`
+		Country       string `xml:"country"`
+	} `xml:"location"`
+	Members struct {
+		Count   int `xml:"count,attr"`
+		Page    int `xml:"page,attr"`
+		Members []struct {
+			Name string `xml:"name,attr"`
+			Date string `xml:"date,attr"`
+		} `xml:"member"`
+	} `xml:"members"`
+}
+
+type Guild struct {
+	ID          int           `json:"id"`
+	Name        string        `json:"name"`
+	Created     string        `json:"created"`
+	Category    string        `json:"category,omitempty"`
+	Website     string        `json:"website,omitempty"`
+	Manager     string        `json:"manager,omitempty"`
+	Location    string        `json:"location,omitempty"`
+	Description string        `json:"description,omitempty"`
+	URL         string        `json:"url"`
+	MemberCount int           `json:"member_count"`
+	Page        int           `json:"page,omitempty"`
+	TotalPages  int           `json:"total_pages,omitempty"`
+	Members     []GuildMember `json:"members,omitempty"`
+}
+
+type GuildMember struct {
+	Username string `json:"username"`
+	Joined   string `json:"joined"`
+}
+
+type GuildLibrary struct {
+	GuildID        int                `json:"guild_id"`
+	GuildName      string             `json:"guild_name"`
+	MembersScanned int                `json:"members_scanned"`
+	MembersSkipped int                `json:"members_skipped,omitempty"`
+	MembersFailed  []GuildMemberError `json:"members_failed,omitempty"`
+	UniqueGames    int                `json:"unique_games"`
+	MatchingGames  int                `json:"matching_games"`
+	Games          []GuildLibraryGame `json:"games"`
+}
+
+type GuildMemberError struct {
+	Username string `json:"username"`
+	Error    string `json:"error"`
+}
+
+type GuildLibraryGame struct {
+	GameID        int      `json:"game_id"`
+	Name          string   `json:"name"`
+	YearPublished int      `json:"year_published"`
+	OwnerCount    int      `json:"owner_count"`
+	Owners        []string `json:"owners"`
+	ForTrade      []string `json:"for_trade,omitempty"`
+}
+
+const (
+	// guildMembersPerPage is the number of members the guild API returns per page.
+	guildMembersPerPage = 25
+	// guildRequestInterval spaces out the collection requests of bgg-guild-library, which BGG
+	// otherwise throttles.
+	guildRequestInterval = 500 * time.Millisecond
+	guildWorkers         = 3
+)
+
+func GuildTool() (mcp.Tool, server.ToolHandlerFunc) {
+	tool := mcp.NewTool("bgg-guild",
+		mcp.WithDescription("Get a BoardGameGeek (BGG) guild's details and its member list, a page at a time"),
+		mcp.WithNumber("id",
+			mcp.Required(),
+			mcp.Description("The guild ID, from a URL like boardgamegeek.com/guild/1234"),
+		),
+		mcp.WithBoolean("members",
+			mcp.Description("Include the member list (default: true)"),
+		),
+		mcp.WithNumber("page",
+			mcp.Description(fmt.Sprintf("Page of members to return, %d per page (default: 1)", guildMembersPerPage)),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		arguments := request.GetArguments()
+
+		id, ok := arguments["id"].(float64)
+		if !ok || id <= 0 {
+			return mcp.NewToolResultError("A guild ID is required"), nil
+		}
+		includeMembers := true
+		if m, ok := arguments["members"].(bool); ok {
+			includeMembers = m
+		}
+		page := 1
+		if p, ok := arguments["page"].(float64); ok && p >= 1 {
+			page = int(p)
+		}
+
+		raw, err := fetchGuild(ctx, int(id), includeMembers, page)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		guild := Guild{
+			ID:          raw.ID,
+			Name:        html.UnescapeString(raw.Name),
+			Created:     raw.Created,
+			Category:    raw.Category,
+			Website:     raw.Website,
+			Manager:     raw.Manager,
+			Location:    joinNonEmpty(raw.Location.City, raw.Location.StateOrRegion, raw.Location.Country),
+			Description: strings.TrimSpace(html.UnescapeString(raw.Description)),
+			URL:         fmt.Sprintf("https://boardgamegeek.com/guild/%d", raw.ID),
+			MemberCount: raw.Members.Count,
+		}
+		if includeMembers {
+			guild.Page = page
+			guild.TotalPages = (raw.Members.Count + guildMembersPerPage - 1) / guildMembersPerPage
+			for _, m := range raw.Members.Members {
+				guild.Members = append(guild.Members, GuildMember{Username: m.Name, Joined: m.Date})
+			}
+		}
+
+		out, _ := json.Marshal(guild)
+		return mcp.NewToolResultText(string(out)), nil
+	}
+
+	return tool, handler
+}
+
+func GuildLibraryTool() (mcp.Tool, server.ToolHandlerFunc) {
+	tool := mcp.NewTool("bgg-guild-library",
+		mcp.WithDescription("Combine the owned collections of a BoardGameGeek (BGG) guild's members into a shared library showing who owns what. Use it to answer 'does anyone in the guild own X?' or to produce a shared-library report. Every member's whole collection is read even when looking for one game, since BGG can't search a collection by name, so this is slow for large guilds; lower max_members for a quicker, partial answer."),
+		mcp.WithNumber("id",
+			mcp.Required(),
+			mcp.Description("The guild ID, from a URL like boardgamegeek.com/guild/1234"),
+		),
+		mcp.WithString("game",
+			mcp.Description("Only list games whose name contains this text. This narrows the result, not the collections read."),
+		),
+		mcp.WithNumber("max_members",
+			mcp.Description("Maximum number of members whose collections are read (default: 50, maximum: 250)"),
+		),
+		mcp.WithNumber("min_owners",
+			mcp.Description("Only list games owned by at least this many members (default: 1)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of games to list (default: 100)"),
+		),
+		mcp.WithString("subtype",
+			mcp.Enum("boardgame", "boardgameexpansion"),
+			mcp.Description("Only include base games ('boardgame') or expansions ('boardgameexpansion')"),
+		),
+		withFormatArgument(FormatJSON, FormatCSV, FormatMarkdown),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		arguments := request.GetArguments()
+
+		id, ok := arguments["id"].(float64)
+		if !ok || id <= 0 {
+			return mcp.NewToolResultError("A guild ID is required"), nil
+		}
+		maxMembers := 50
+		if m, ok := arguments["max_members"].(float64); ok && m >= 1 {
+			maxMembers = min(int(m), 250)
+		}
+		minOwners := 1
+		if m, ok := arguments["min_owners"].(float64); ok && m >= 1 {
+			minOwners = int(m)
+		}
+		limit := 100
+		if l, ok := arguments["limit"].(float64); ok && l >= 1 {
+			limit = int(l)
+		}
+		gameFilter := ""
+		if g, ok := arguments["game"].(string); ok {
+			gameFilter = strings.ToLower(strings.TrimSpace(g))
+		}
+
+		guild, members, err := fetchGuildMembers(ctx, int(id), maxMembers)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		options := []collection.CollectionOption{collection.WithOwned(true)}
+		if subtype, ok := arguments["subtype"].(string); ok {
+			if subtype == "boardgame" {
+				options = append(options, collection.WithSubtype("boardgame"), collection.WithExcludeSubtype("boardgameexpansion"))
+			} else {
+				options = append(options, collection.WithSubtype(subtype))
+			}
+		}
+
+		collections, failed := fetchMemberCollections(ctx, members, options)
+
+		library := aggregateGuildLibrary(collections)
+		result := GuildLibrary{
+			GuildID:        guild.ID,
+			GuildName:      html.UnescapeString(guild.Name),
+			MembersScanned: len(collections),
+			MembersSkipped: max(guild.Members.Count-len(members), 0),
+			MembersFailed:  failed,
+			UniqueGames:    len(library),
+		}
+		for _, game := range library {
+			if game.OwnerCount < minOwners {
+				continue
+			}
+			if gameFilter != "" && !strings.Contains(strings.ToLower(game.Name), gameFilter) {
+				continue
+			}
+			result.Games = append(result.Games, game)
+		}
+		result.MatchingGames = len(result.Games)
+		if len(result.Games) > limit {
+			result.Games = result.Games[:limit]
+		}
+
+		return formatToolResult(formatArgument(arguments), result, tableFor(func() table {
+			return guildLibraryTable(result.Games)
+		}))
+	}
+
+	return tool, handler
+}
+
+func fetchGuild(ctx context.Context, id int, members bool, page int) (*guildXML, error) {
+	url := fmt.Sprintf("https://boardgamegeek.com/xmlapi2/guild?id=%d", id)
+	if members {
+		url += fmt.Sprintf("&members=1&page=%d", page)
+	}
+
+	done := traceBGG(ctx, "guild", tracing.Int("bgg.guild_id", id), tracing.Int("bgg.page", page))
+	var guild guildXML
+	err := fetchXML(ctx, url, &guild)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching guild: %v", err)
+	}
+	if guild.Name == "" {
+		return nil, fmt.Errorf("guild %d was not found", id)
+	}
+	return &guild, nil
+}
+
+// fetchGuildMembers reads up to limit member usernames, page by page.
+func fetchGuildMembers(ctx context.Context, id, limit int) (*guildXML, []string, error) {
+	var guild *guildXML
+	var members []string
+	for page := 1; len(members) < limit; page++ {
+		g, err := fetchGuild(ctx, id, true, page)
+		if err != nil {
+			return nil, nil, err
+		}
+		if guild == nil {
+			guild = g
+		}
+		for _, m := range g.Members.Members {
+			members = append(members, m.Name)
+		}
+		if len(g.Members.Members) == 0 || page*guildMembersPerPage >= g.Members.Count {
+			break
+		}
+	}
+	if len(members) > limit {
+		members = members[:limit]
+	}
+	return guild, members, nil
+}
+
+// fetchMemberCollections reads each member's collection with a few workers, starting at most
+// one request every guildRequestInterval.
+func fetchMemberCollections(ctx context.Context, members []string, options []collection.CollectionOption) (map[string]*collection.Collection, []GuildMemberError) {
+	ticker := time.NewTicker(guildRequestInterval)
+	defer ticker.Stop()
+
+	var mu sync.Mutex
+	collections := map[string]*collection.Collection{}
+	var failed []GuildMemberError
+
+	queue := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < guildWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for username := range queue {
+				select {
+				case <-ctx.Done():
+					mu.Lock()
+					failed = append(failed, GuildMemberError{Username: username, Error: ctx.Err().Error()})
+					mu.Unlock()
+					continue
+				case <-ticker.C:
+				}
+
+				done := traceBGG(ctx, "collection.Query", tracing.Username("bgg.username", username), tracing.Bool("bgg.owned", true))
+				c, err := collection.Query(username, options...)
+				done(err)
+
+				mu.Lock()
+				if err != nil {
+					failed = append(failed, GuildMemberError{Username: username, Error: err.Error()})
+				} else {
+					collections[username] = c
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, username := range members {
+		queue <- username
+	}
+	close(queue)
+	wg.Wait()
+
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Username < failed[j].Username })
+	return collections, failed
+}
+
+// aggregateGuildLibrary merges the collections into one entry per game, most owned first.
+func aggregateGuildLibrary(collections map[string]*collection.Collection) []GuildLibraryGame {
+	games := map[int]*GuildLibraryGame{}
+	for username, c := range collections {
+		seen := map[int]bool{}
+		for _, item := range c.Items {
+			if seen[item.ObjectID] {
+				continue
+			}
+			seen[item.ObjectID] = true
+
+			game, ok := games[item.ObjectID]
+			if !ok {
+				game = &GuildLibraryGame{GameID: item.ObjectID, Name: item.Name, YearPublished: item.YearPublished}
+				games[item.ObjectID] = game
+			}
+			game.Owners = append(game.Owners, username)
+			if item.Status.ForTrade == 1 {
+				game.ForTrade = append(game.ForTrade, username)
+			}
+		}
+	}
+
+	library := make([]GuildLibraryGame, 0, len(games))
+	for _, game := range games {
+		sort.Strings(game.Owners)
+		sort.Strings(game.ForTrade)
+		game.OwnerCount = len(game.Owners)
+		library = append(library, *game)
+	}
+	sort.Slice(library, func(i, j int) bool {
+		if library[i].OwnerCount != library[j].OwnerCount {
+			return library[i].OwnerCount > library[j].OwnerCount
+		}
+		return strings.ToLower(library[i].Name) < strings.ToLower(library[j].Name)
+	})
+	return library
+}
+
+func guildLibraryTable(games []GuildLibraryGame) table {
+	t := table{Headers: []string{"game_id", "name", "year", "owner_count", "owners", "for_trade"}}
+	for _, g := range games {
+		t.Rows = append(t.Rows, []string{
+			strconv.Itoa(g.GameID),
+			g.Name,
+			strconv.Itoa(g.YearPublished),
+			strconv.Itoa(g.OwnerCount),
+			strings.Join(g.Owners, ", "),
+			strings.Join(g.ForTrade, ", "),
+		})
+	}
+	return t
+}
+
+func joinNonEmpty(parts ...string) string {
+	var kept []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, ", ")
+}
@@ -1,9 +1,19 @@
 package tools
 
 import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
+	"html"
+	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
 
+	"github.com/kkjdanie/bgg-mcp/tracing"
 	"github.com/kkjdaniel/gogeek/search"
 	"github.com/kkjdaniel/gogeek/thing"
 )
@@ -21,12 +31,16 @@ type EssentialGameInfo struct {
 	PlayTime     string   `json:"play_time"`
 	MinAge       int      `json:"min_age"`
 	Designer     string   `json:"designer"`
+	DesignerIDs  []int    `json:"designer_ids,omitempty"`
 	Publisher    string   `json:"publisher"`
+	PublisherIDs []int    `json:"publisher_ids,omitempty"`
 	Type         string   `json:"type"`
 	Thumbnail    string   `json:"thumbnail"`
 	Image        string   `json:"image"`
 	Categories   []string `json:"categories"`
 	Mechanics    []string `json:"mechanics"`
+	Families     []string `json:"families,omitempty"`
+	FamilyIDs    []int    `json:"family_ids,omitempty"`
 	NumRatings   int      `json:"num_ratings"`
 	Owned        int      `json:"owned"`
 	Wishing      int      `json:"wishing"`
@@ -89,12 +103,17 @@ func extractEssentialInfo(item thing.Item) EssentialGameInfo {
 		switch link.Type {
 		case "boardgamedesigner":
 			designers = append(designers, link.Value)
+			info.DesignerIDs = append(info.DesignerIDs, link.ID)
 		case "boardgamepublisher":
 			publishers = append(publishers, link.Value)
+			info.PublisherIDs = append(info.PublisherIDs, link.ID)
 		case "boardgamecategory":
 			categories = append(categories, link.Value)
 		case "boardgamemechanic":
 			mechanics = append(mechanics, link.Value)
+		case "boardgamefamily":
+			info.Families = append(info.Families, link.Value)
+			info.FamilyIDs = append(info.FamilyIDs, link.ID)
 		}
 	}
 	
@@ -118,14 +137,18 @@ func extractEssentialInfoList(items []thing.Item) []EssentialGameInfo {
 	return result
 }
 
-func findBestGameMatch(gameName string) (*search.SearchResult, error) {
+func findBestGameMatch(ctx context.Context, gameName string) (*search.SearchResult, error) {
+	done := traceBGG(ctx, "search.Query", tracing.String("bgg.query", gameName), tracing.Bool("bgg.exact", true))
 	searchResults, err := search.Query(gameName, true)
+	done(err)
 	if err != nil {
 		return nil, fmt.Errorf("search failed: %w", err)
 	}
 	
 	if len(searchResults.Items) == 0 {
+		done := traceBGG(ctx, "search.Query", tracing.String("bgg.query", gameName), tracing.Bool("bgg.exact", false))
 		searchResults, err = search.Query(gameName, false)
+		done(err)
 		if err != nil {
 			return nil, fmt.Errorf("search failed: %w", err)
 		}
@@ -160,3 +183,143 @@ func findBestGameMatch(gameName string) (*search.SearchResult, error) {
 	
 	return bestMatch, nil
 }
+
+// fetchJSON performs a GET request and decodes a JSON response body into v.
+func fetchJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", req.URL.Host, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("error parsing response: %w", err)
+	}
+	return nil
+}
+
+// errBGGQueued is returned by fetchXML when BGG accepted the request but has not prepared the
+// response yet, which it signals with 202 Accepted.
+var errBGGQueued = errors.New("BGG queued the request")
+
+// fetchXML performs a GET request and decodes an XML response body into v.
+func fetchXML(ctx context.Context, url string, v any) error {
+	resp, err := httpGet(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusAccepted {
+		return errBGGQueued
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", resp.Request.URL.Host, resp.StatusCode)
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("error parsing response: %w", err)
+	}
+	return nil
+}
+
+// httpGet performs a GET request bound to ctx, so upstream calls are cancelled with the tool
+// call and traced as its children.
+func httpGet(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(req)
+}
+
+// fetchThings queries thing details in batches of 20, the BGG API maximum per request.
+func fetchThings(ctx context.Context, ids []int) ([]thing.Item, error) {
+	var items []thing.Item
+	maxBatch := 20
+
+	for i := 0; i < len(ids); i += maxBatch {
+		end := i + maxBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		done := traceBGG(ctx, "thing.Query", tracing.Int("bgg.ids", end-i))
+		things, err := thing.Query(ids[i:end])
+		done(err)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching game details: %v", err)
+		}
+		items = append(items, things.Items...)
+	}
+
+	return items, nil
+}
+
+// boardGameRank returns the overall board game rank from the item statistics, or 0 when unranked.
+func boardGameRank(item thing.Item) int {
+	if item.Statistics == nil {
+		return 0
+	}
+	for _, rank := range item.Statistics.Ranks {
+		if rank.Name == "boardgame" {
+			if n, err := strconv.Atoi(rank.Value); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+var (
+	htmlBreakPattern = regexp.MustCompile(`(?i)<\s*(br|/p|/div|/li|/blockquote)\s*/?>`)
+	htmlTagPattern   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// stripHTML removes markup from BGG description and article bodies, keeping line breaks.
+func stripHTML(s string) string {
+	s = htmlBreakPattern.ReplaceAllString(s, "\n")
+	s = htmlTagPattern.ReplaceAllString(s, "")
+	return html.UnescapeString(s)
+}
+
+// resolveGame returns the game ID and name from an "id" or "name" tool argument. The name is
+// empty when the game was given by ID.
+func resolveGame(ctx context.Context, arguments map[string]interface{}) (int, string, error) {
+	if idVal, ok := arguments["id"]; ok && idVal != nil {
+		switch v := idVal.(type) {
+		case float64:
+			return int(v), "", nil
+		case string:
+			gameID, err := strconv.Atoi(v)
+			if err != nil {
+				return 0, "", fmt.Errorf("invalid game ID format")
+			}
+			return gameID, "", nil
+		}
+		return 0, "", fmt.Errorf("invalid game ID type")
+	}
+
+	if name, ok := arguments["name"].(string); ok && name != "" {
+		bestMatch, err := findBestGameMatch(ctx, name)
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to find game: %w", err)
+		}
+		return bestMatch.ID, bestMatch.Name.Value, nil
+	}
+
+	return 0, "", fmt.Errorf("either 'name' or 'id' parameter is required")
+}
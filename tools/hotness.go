@@ -15,9 +15,11 @@ func HotnessTool() (mcp.Tool, server.ToolHandlerFunc) {
 	)
 
 	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		done := traceBGG(ctx, "hot.Query")
 		hotItems, err := hot.Query(hot.ItemTypeBoardGame)
+		done(err)
 		if err != nil {
-			return mcp.NewToolResultText(err.Error()), nil
+			return mcp.NewToolResultError(err.Error()), nil
 		}
 
 		if len(hotItems.Items) > 0 {
@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// UsernameHeader and UsernameQueryParam let HTTP clients say which BGG user "SELF" refers to,
+// so one deployment can serve several people.
+const (
+	UsernameHeader     = "X-BGG-Username"
+	UsernameQueryParam = "bgg_username"
+)
+
+// sessionIdleTimeout is how long a session's username is remembered without activity.
+// Streamable HTTP clients often end without a DELETE, so entries also expire.
+const sessionIdleTimeout = 24 * time.Hour
+
+type usernameKey struct{}
+
+// WithUsername returns ctx carrying the BGG username "SELF" resolves to for this request.
+func WithUsername(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, usernameKey{}, username)
+}
+
+func requestUsername(ctx context.Context) string {
+	username, _ := ctx.Value(usernameKey{}).(string)
+	return username
+}
+
+// IdentityFromRequest reads the username from the X-BGG-Username header or the bgg_username
+// query parameter. It is used as the streamable HTTP context func for /mcp.
+func IdentityFromRequest(ctx context.Context, r *http.Request) context.Context {
+	username := strings.TrimSpace(r.Header.Get(UsernameHeader))
+	if username == "" {
+		username = strings.TrimSpace(r.URL.Query().Get(UsernameQueryParam))
+	}
+	if username == "" {
+		return ctx
+	}
+	return WithUsername(ctx, username)
+}
+
+// IdentityMiddleware applies IdentityFromRequest to REST requests.
+func IdentityMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(IdentityFromRequest(r.Context(), r)))
+	})
+}
+
+// RegisterIdentityHooks binds a username to each MCP session at initialize time, taken from the
+// client's capabilities.experimental.bgg.username preference or, failing that, from the HTTP
+// header or query parameter of the initialize request.
+func RegisterIdentityHooks(hooks *server.Hooks) {
+	hooks.AddAfterInitialize(func(ctx context.Context, id any, request *mcp.InitializeRequest, result *mcp.InitializeResult) {
+		session := server.ClientSessionFromContext(ctx)
+		if session == nil || session.SessionID() == "" {
+			return
+		}
+		username := initializeUsername(request)
+		if username == "" {
+			username = requestUsername(ctx)
+		}
+		if username != "" {
+			sessionUsernames.set(session.SessionID(), username)
+		}
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		sessionUsernames.delete(session.SessionID())
+	})
+}
+
+// initializeUsername returns the username from an initialize request's
+// {"capabilities": {"experimental": {"bgg": {"username": "..."}}}}.
+func initializeUsername(request *mcp.InitializeRequest) string {
+	bgg, ok := request.Params.Capabilities.Experimental["bgg"].(map[string]any)
+	if !ok {
+		return ""
+	}
+	username, _ := bgg["username"].(string)
+	return strings.TrimSpace(username)
+}
+
+// resolveUsername returns name unless it is "SELF", which resolves to the request's header or
+// query parameter, then the username bound to the MCP session, then the configured username
+// or BGG_USERNAME.
+func resolveUsername(ctx context.Context, name string) (string, error) {
+	if !strings.EqualFold(name, "SELF") {
+		return name, nil
+	}
+	if username := requestUsername(ctx); username != "" {
+		return username, nil
+	}
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		if username, ok := sessionUsernames.get(session.SessionID()); ok {
+			return username, nil
+		}
+	}
+	if username := defaultUsername(); username != "" {
+		return username, nil
+	}
+	return "", fmt.Errorf("no BGG username is set for this session. Send the %s header, set capabilities.experimental.bgg.username when initializing, or set BGG_USERNAME (or bgg.username in the config file). Otherwise provide your specific username instead of 'SELF'.", UsernameHeader)
+}
+
+// sessionUsernames remembers the username each MCP session chose at initialize time.
+var sessionUsernames = &sessionStore{entries: map[string]*sessionEntry{}}
+
+type sessionStore struct {
+	mu      sync.Mutex
+	entries map[string]*sessionEntry
+}
+
+type sessionEntry struct {
+	username string
+	lastSeen time.Time
+}
+
+func (s *sessionStore) set(sessionID, username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, e := range s.entries {
+		if now.Sub(e.lastSeen) > sessionIdleTimeout {
+			delete(s.entries, id)
+		}
+	}
+	s.entries[sessionID] = &sessionEntry{username: username, lastSeen: now}
+}
+
+func (s *sessionStore) get(sessionID string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[sessionID]
+	if !ok || time.Since(e.lastSeen) > sessionIdleTimeout {
+		return "", false
+	}
+	e.lastSeen = time.Now()
+	return e.username, true
+}
+
+func (s *sessionStore) delete(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, sessionID)
+}
@@ -0,0 +1,315 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kkjdanie/bgg-mcp/tracing"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// PlayPlayer is one player in a logged play.
+type PlayPlayer struct {
+	Name     string  `json:"name"`
+	Username string  `json:"username,omitempty"`
+	Score    string  `json:"score,omitempty"`
+	Color    string  `json:"color,omitempty"`
+	Position string  `json:"position,omitempty"`
+	Win      bool    `json:"win,omitempty"`
+	New      bool    `json:"new,omitempty"`
+	Rating   float64 `json:"rating,omitempty"`
+}
+
+// Play is a play as shown in the bgg-log-play preview.
+type Play struct {
+	GameID     int          `json:"game_id"`
+	GameName   string       `json:"game_name,omitempty"`
+	Date       string       `json:"date"`
+	Quantity   int          `json:"quantity"`
+	Minutes    int          `json:"minutes,omitempty"`
+	Location   string       `json:"location,omitempty"`
+	Comments   string       `json:"comments,omitempty"`
+	Incomplete bool         `json:"incomplete,omitempty"`
+	NoWinStats bool         `json:"no_win_stats,omitempty"`
+	Players    []PlayPlayer `json:"players,omitempty"`
+}
+
+type LogPlayResult struct {
+	Status   string `json:"status"`
+	Account  string `json:"account"`
+	Play     Play   `json:"play"`
+	PlayID   string `json:"play_id,omitempty"`
+	NumPlays int    `json:"num_plays,omitempty"`
+	Message  string `json:"message"`
+}
+
+// geekplayRequest is the body the BGG website posts to geekplay.php when saving a play.
+type geekplayRequest struct {
+	Action     string           `json:"action"`
+	Ajax       int              `json:"ajax"`
+	ObjectType string           `json:"objecttype"`
+	ObjectID   string           `json:"objectid"`
+	PlayDate   string           `json:"playdate"`
+	Quantity   int              `json:"quantity"`
+	Length     int              `json:"length"`
+	Location   string           `json:"location"`
+	Comments   string           `json:"comments"`
+	Incomplete bool             `json:"incomplete"`
+	NoWinStats bool             `json:"nowinstats"`
+	Players    []geekplayPlayer `json:"players"`
+}
+
+type geekplayPlayer struct {
+	Name     string  `json:"name"`
+	Username string  `json:"username"`
+	Score    string  `json:"score"`
+	Color    string  `json:"color"`
+	Position string  `json:"position"`
+	Win      bool    `json:"win"`
+	New      bool    `json:"new"`
+	Rating   float64 `json:"rating"`
+}
+
+type geekplayResponse struct {
+	PlayID   json.Number `json:"playid"`
+	NumPlays int         `json:"numplays"`
+	Error    string      `json:"error"`
+}
+
+func LogPlayTool() (mcp.Tool, server.ToolHandlerFunc) {
+	tool := mcp.NewTool("bgg-log-play",
+		mcp.WithDescription("Record a play of a board game to the user's BoardGameGeek (BGG) account. Without confirm=true this only returns a preview and writes nothing: show the preview to the user and call again with confirm=true only after they agree. Calling twice with confirm=true logs the play twice."),
+		mcp.WithTitleAnnotation("Log a play on BGG"),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(false),
+		mcp.WithOpenWorldHintAnnotation(true),
+		mcp.WithString("name",
+			mcp.Description("The name of the board game"),
+		),
+		mcp.WithNumber("id",
+			mcp.Description("The BoardGameGeek ID of the board game (preferred over name)"),
+		),
+		mcp.WithString("date",
+			mcp.Description("Date of the play as YYYY-MM-DD (default: today)"),
+		),
+		mcp.WithNumber("quantity",
+			mcp.Description("Number of plays to record (default: 1)"),
+		),
+		mcp.WithNumber("minutes",
+			mcp.Description("Length of the play in minutes"),
+		),
+		mcp.WithString("location",
+			mcp.Description("Where the game was played"),
+		),
+		mcp.WithString("comments",
+			mcp.Description("Comments about the play"),
+		),
+		mcp.WithBoolean("incomplete",
+			mcp.Description("The game was not finished"),
+		),
+		mcp.WithBoolean("no_win_stats",
+			mcp.Description("Exclude this play from win statistics"),
+		),
+		mcp.WithArray("players",
+			mcp.Description("The players, each with a name and optionally their BGG username, score, color, start position, win flag, new-player flag and rating (1-10)"),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name":     map[string]any{"type": "string"},
+					"username": map[string]any{"type": "string"},
+					"score":    map[string]any{"type": "string"},
+					"color":    map[string]any{"type": "string"},
+					"position": map[string]any{"type": "string"},
+					"win":      map[string]any{"type": "boolean"},
+					"new":      map[string]any{"type": "boolean"},
+					"rating":   map[string]any{"type": "number"},
+				},
+			}),
+		),
+		mcp.WithBoolean("confirm",
+			mcp.Description("Set to true to write the play to BGG. Only set this after the user has approved the preview."),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		arguments := request.GetArguments()
+
+		username, err := accountUsername(ctx)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		play, err := parsePlay(arguments)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		gameID, gameName, err := resolveGame(ctx, arguments)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if gameName == "" {
+			if items, err := fetchThings(ctx, []int{gameID}); err == nil && len(items) > 0 && len(items[0].Name) > 0 {
+				gameName = items[0].Name[0].Value
+			}
+		}
+		if gameName == "" {
+			gameName = fmt.Sprintf("game %d", gameID)
+		}
+		play.GameID = gameID
+		play.GameName = gameName
+
+		result := LogPlayResult{Account: username, Play: play}
+
+		confirm, _ := arguments["confirm"].(bool)
+		if !confirm {
+			result.Status = "preview"
+			result.Message = "Nothing has been written to BGG. Show this play to the user and call bgg-log-play again with the same arguments and confirm=true once they approve it."
+			out, _ := json.Marshal(result)
+			return mcp.NewToolResultText(string(out)), nil
+		}
+
+		done := traceBGG(ctx, "geekplay.save",
+			tracing.Int("bgg.id", gameID),
+			tracing.Username("bgg.username", username),
+		)
+		var resp geekplayResponse
+		err = account.post(ctx, "/geekplay.php", toGeekplayRequest(play), &resp)
+		if err == nil && resp.Error != "" {
+			err = fmt.Errorf("BGG did not log the play: %s", resp.Error)
+		}
+		done(err)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		invalidateUpstreamCache(username)
+
+		result.Status = "logged"
+		result.PlayID = resp.PlayID.String()
+		result.NumPlays = resp.NumPlays
+		result.Message = fmt.Sprintf("Logged %d play(s) of %s on %s for %s.", play.Quantity, gameName, play.Date, username)
+		out, _ := json.Marshal(result)
+		return mcp.NewToolResultText(string(out)), nil
+	}
+
+	return tool, handler
+}
+
+// parsePlay reads and validates the play arguments other than the game.
+func parsePlay(arguments map[string]interface{}) (Play, error) {
+	play := Play{
+		Date:     time.Now().Format("2006-01-02"),
+		Quantity: 1,
+	}
+
+	if d, ok := arguments["date"].(string); ok && d != "" {
+		date, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			return play, fmt.Errorf("invalid date '%s': use YYYY-MM-DD", d)
+		}
+		if date.After(time.Now()) {
+			return play, fmt.Errorf("the play date %s is in the future", d)
+		}
+		play.Date = d
+	}
+	if q, ok := arguments["quantity"].(float64); ok {
+		if q < 1 {
+			return play, fmt.Errorf("quantity must be at least 1")
+		}
+		play.Quantity = int(q)
+	}
+	if m, ok := arguments["minutes"].(float64); ok {
+		if m < 0 {
+			return play, fmt.Errorf("minutes must not be negative")
+		}
+		play.Minutes = int(m)
+	}
+	play.Location, _ = arguments["location"].(string)
+	play.Comments, _ = arguments["comments"].(string)
+	play.Incomplete, _ = arguments["incomplete"].(bool)
+	play.NoWinStats, _ = arguments["no_win_stats"].(bool)
+
+	if raw, ok := arguments["players"]; ok && raw != nil {
+		list, ok := raw.([]interface{})
+		if !ok {
+			return play, fmt.Errorf("players must be an array of objects")
+		}
+		for i, entry := range list {
+			p, ok := entry.(map[string]interface{})
+			if !ok {
+				return play, fmt.Errorf("player %d must be an object", i+1)
+			}
+			player := PlayPlayer{
+				Name:     strings.TrimSpace(stringArg(p["name"])),
+				Username: strings.TrimSpace(stringArg(p["username"])),
+				Score:    stringArg(p["score"]),
+				Color:    stringArg(p["color"]),
+				Position: stringArg(p["position"]),
+			}
+			player.Win, _ = p["win"].(bool)
+			player.New, _ = p["new"].(bool)
+			if r, ok := p["rating"].(float64); ok {
+				if r < 1 || r > 10 {
+					return play, fmt.Errorf("player %d: rating must be between 1 and 10", i+1)
+				}
+				player.Rating = r
+			}
+			if player.Name == "" {
+				player.Name = player.Username
+			}
+			if player.Name == "" {
+				return play, fmt.Errorf("player %d needs a name or username", i+1)
+			}
+			play.Players = append(play.Players, player)
+		}
+	}
+
+	return play, nil
+}
+
+// stringArg returns a string or number argument as a string.
+func stringArg(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+func toGeekplayRequest(play Play) geekplayRequest {
+	req := geekplayRequest{
+		Action:     "save",
+		Ajax:       1,
+		ObjectType: "thing",
+		ObjectID:   strconv.Itoa(play.GameID),
+		PlayDate:   play.Date,
+		Quantity:   play.Quantity,
+		Length:     play.Minutes,
+		Location:   play.Location,
+		Comments:   play.Comments,
+		Incomplete: play.Incomplete,
+		NoWinStats: play.NoWinStats,
+		Players:    []geekplayPlayer{},
+	}
+	for _, p := range play.Players {
+		req.Players = append(req.Players, geekplayPlayer{
+			Name:     p.Name,
+			Username: p.Username,
+			Score:    p.Score,
+			Color:    p.Color,
+			Position: p.Position,
+			Win:      p.Win,
+			New:      p.New,
+			Rating:   p.Rating,
+		})
+	}
+	return req
+}
@@ -0,0 +1,321 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kkjdanie/bgg-mcp/tracing"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// marketplaceXML is the XML API v2 thing response with GeekMarket listings.
+type marketplaceXML struct {
+	Items []struct {
+		ID    int `xml:"id,attr"`
+		Names []struct {
+			Type  string `xml:"type,attr"`
+			Value string `xml:"value,attr"`
+		} `xml:"name"`
+		Listings []struct {
+			ListDate struct {
+				Value string `xml:"value,attr"`
+			} `xml:"listdate"`
+			Price struct {
+				Currency string `xml:"currency,attr"`
+				Value    string `xml:"value,attr"`
+			} `xml:"price"`
+			Condition struct {
+				Value string `xml:"value,attr"`
+			} `xml:"condition"`
+			Notes struct {
+				Value string `xml:"value,attr"`
+			} `xml:"notes"`
+			Link struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+		} `xml:"marketplacelistings>listing"`
+	} `xml:"item"`
+}
+
+type MarketplaceResult struct {
+	Currency    string            `json:"currency"`
+	RatesDate   string            `json:"rates_date"`
+	Games       []MarketplaceGame `json:"games"`
+	Unconverted []string          `json:"unconverted_currencies,omitempty"`
+	Note        string            `json:"note"`
+}
+
+// marketplaceCountryNote is returned with every result, as callers asking for listings near
+// them would otherwise assume they got them.
+const marketplaceCountryNote = "Listings are not filtered by country: the BGG XML API does not report where a seller is. Open a listing's link to check where it ships from."
+
+type MarketplaceGame struct {
+	GameID       int                  `json:"game_id"`
+	Name         string               `json:"name"`
+	ListingCount int                  `json:"listing_count"`
+	Matching     int                  `json:"matching"`
+	Cheapest     *MarketplaceListing  `json:"cheapest,omitempty"`
+	Listings     []MarketplaceListing `json:"listings"`
+}
+
+type MarketplaceListing struct {
+	Price            float64 `json:"price"`
+	Currency         string  `json:"currency"`
+	OriginalPrice    float64 `json:"original_price"`
+	OriginalCurrency string  `json:"original_currency"`
+	Condition        string  `json:"condition"`
+	Listed           string  `json:"listed"`
+	Notes            string  `json:"notes,omitempty"`
+	Link             string  `json:"link"`
+}
+
+// marketplaceConditions orders GeekMarket conditions from best to worst.
+var marketplaceConditions = []string{"new", "likenew", "verygood", "good", "acceptable"}
+
+func MarketplaceTool() (mcp.Tool, server.ToolHandlerFunc) {
+	tool := mcp.NewTool("bgg-marketplace",
+		mcp.WithDescription("Get current used and second-hand copies listed on the BoardGameGeek (BGG) GeekMarket for one or more games, with prices converted to one currency. Compare with bgg-price for new retail prices. Listings cannot be filtered by country, because the XML API does not include the seller's location; the result says so, and each listing's link shows where it ships from."),
+		mcp.WithString("name",
+			mcp.Description("The name of the board game"),
+		),
+		mcp.WithNumber("id",
+			mcp.Description("The BoardGameGeek ID of the board game"),
+		),
+		mcp.WithArray("ids",
+			mcp.Description("Array of BoardGameGeek IDs to get listings for several games at once (maximum 20)"),
+		),
+		mcp.WithString("currency",
+			mcp.Description(fmt.Sprintf("Currency to convert prices to (default: %s)", CurrentSettings().Currency)),
+		),
+		mcp.WithString("min_condition",
+			mcp.Enum(marketplaceConditions...),
+			mcp.Description("Only include listings in this condition or better"),
+		),
+		mcp.WithString("sort",
+			mcp.Enum("price", "price_desc", "newest"),
+			mcp.Description("Sort listings by price, cheapest first (default), most expensive first, or newest first"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum listings per game (default: 10)"),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		arguments := request.GetArguments()
+
+		ids, err := gameIDsArgument(ctx, arguments, 20)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		currency := CurrentSettings().Currency
+		if c, ok := arguments["currency"].(string); ok && c != "" {
+			currency = strings.ToUpper(c)
+		}
+		if !knownCurrency(currency) {
+			return mcp.NewToolResultError(fmt.Sprintf("No exchange rate for currency '%s'", currency)), nil
+		}
+		// Without min_condition every listing is kept, including conditions not in
+		// marketplaceConditions.
+		maxCondition := -1
+		if c, ok := arguments["min_condition"].(string); ok && c != "" {
+			maxCondition = conditionRank(c)
+		}
+		sortBy, _ := arguments["sort"].(string)
+		limit := 10
+		if l, ok := arguments["limit"].(float64); ok && l >= 1 {
+			limit = int(l)
+		}
+
+		games, unconverted, err := fetchMarketplace(ctx, ids, currency)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error fetching marketplace listings: %v", err)), nil
+		}
+
+		result := MarketplaceResult{Currency: currency, RatesDate: fxRatesDate(), Games: games, Unconverted: unconverted, Note: marketplaceCountryNote}
+		for i := range result.Games {
+			game := &result.Games[i]
+			var listings []MarketplaceListing
+			for _, l := range game.Listings {
+				if maxCondition < 0 || conditionRank(l.Condition) <= maxCondition {
+					listings = append(listings, l)
+				}
+			}
+			game.Listings = listings
+			game.Matching = len(listings)
+
+			sortListings(game.Listings, sortBy)
+			if game.Matching > 0 {
+				cheapest := game.Listings[0]
+				for _, l := range game.Listings {
+					if l.Price < cheapest.Price {
+						cheapest = l
+					}
+				}
+				game.Cheapest = &cheapest
+			}
+			if len(game.Listings) > limit {
+				game.Listings = game.Listings[:limit]
+			}
+			if game.Listings == nil {
+				game.Listings = []MarketplaceListing{}
+			}
+		}
+
+		out, err := json.Marshal(result)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error formatting results: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(out)), nil
+	}
+
+	return tool, handler
+}
+
+// fetchMarketplace fetches the GeekMarket listings for ids in batches of 20, converting prices
+// to currency. Listings in currencies without an exchange rate are left out and their
+// currencies returned.
+func fetchMarketplace(ctx context.Context, ids []int, currency string) ([]MarketplaceGame, []string, error) {
+	games := []MarketplaceGame{}
+	unconverted := map[string]bool{}
+
+	for i := 0; i < len(ids); i += 20 {
+		end := i + 20
+		if end > len(ids) {
+			end = len(ids)
+		}
+		strIDs := make([]string, 0, end-i)
+		for _, id := range ids[i:end] {
+			strIDs = append(strIDs, strconv.Itoa(id))
+		}
+		url := "https://boardgamegeek.com/xmlapi2/thing?marketplace=1&id=" + strings.Join(strIDs, ",")
+
+		done := traceBGG(ctx, "thing.marketplace", tracing.Int("bgg.ids", end-i))
+		var resp marketplaceXML
+		err := fetchXML(ctx, url, &resp)
+		done(err)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, item := range resp.Items {
+			game := MarketplaceGame{GameID: item.ID, ListingCount: len(item.Listings)}
+			for _, n := range item.Names {
+				if n.Type == "primary" {
+					game.Name = html.UnescapeString(n.Value)
+				}
+			}
+			for _, l := range item.Listings {
+				original, err := strconv.ParseFloat(l.Price.Value, 64)
+				if err != nil {
+					continue
+				}
+				price, err := convertCurrency(original, l.Price.Currency, currency)
+				if err != nil {
+					unconverted[strings.ToUpper(l.Price.Currency)] = true
+					continue
+				}
+				game.Listings = append(game.Listings, MarketplaceListing{
+					Price:            price,
+					Currency:         currency,
+					OriginalPrice:    original,
+					OriginalCurrency: l.Price.Currency,
+					Condition:        l.Condition.Value,
+					Listed:           l.ListDate.Value,
+					Notes:            strings.TrimSpace(html.UnescapeString(l.Notes.Value)),
+					Link:             l.Link.Href,
+				})
+			}
+			games = append(games, game)
+		}
+	}
+
+	var codes []string
+	for c := range unconverted {
+		codes = append(codes, c)
+	}
+	sort.Strings(codes)
+	return games, codes, nil
+}
+
+// gameIDsArgument reads game IDs from the ids array argument, or resolves a single game from
+// id or name.
+func gameIDsArgument(ctx context.Context, arguments map[string]interface{}, maxIDs int) ([]int, error) {
+	idsVal, ok := arguments["ids"]
+	if !ok || idsVal == nil {
+		id, _, err := resolveGame(ctx, arguments)
+		if err != nil {
+			return nil, err
+		}
+		return []int{id}, nil
+	}
+
+	idsArray, ok := idsVal.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Invalid IDs format - must be an array")
+	}
+	if len(idsArray) > maxIDs {
+		return nil, fmt.Errorf("Too many IDs provided. Maximum %d IDs per request.", maxIDs)
+	}
+
+	var ids []int
+	for _, idVal := range idsArray {
+		switch v := idVal.(type) {
+		case float64:
+			ids = append(ids, int(v))
+		case string:
+			id, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("Invalid ID format: %s", v)
+			}
+			ids = append(ids, id)
+		default:
+			return nil, fmt.Errorf("Invalid ID type in array")
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("At least one ID is required")
+	}
+	return ids, nil
+}
+
+// conditionRank returns the position of condition in marketplaceConditions; unknown
+// conditions rank last.
+func conditionRank(condition string) int {
+	for i, c := range marketplaceConditions {
+		if strings.EqualFold(c, condition) {
+			return i
+		}
+	}
+	return len(marketplaceConditions)
+}
+
+func sortListings(listings []MarketplaceListing, sortBy string) {
+	switch sortBy {
+	case "price_desc":
+		sort.SliceStable(listings, func(i, j int) bool { return listings[i].Price > listings[j].Price })
+	case "newest":
+		// BGG list dates are RFC 1123 strings, so compare them as parsed times.
+		sort.SliceStable(listings, func(i, j int) bool {
+			return parseListDate(listings[i].Listed).After(parseListDate(listings[j].Listed))
+		})
+	default:
+		sort.SliceStable(listings, func(i, j int) bool { return listings[i].Price < listings[j].Price })
+	}
+}
+
+// parseListDate parses a GeekMarket list date, returning the zero time when it is malformed.
+func parseListDate(value string) time.Time {
+	t, err := time.Parse(time.RFC1123Z, value)
+	if err != nil {
+		t, _ = time.Parse(time.RFC1123, value)
+	}
+	return t
+}
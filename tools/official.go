@@ -0,0 +1,172 @@
+package tools
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/kkjdaniel/gogeek/thing"
+)
+
+// OfficialAccountsConfig lists known designer and publisher accounts beyond those matched from
+// a game's credits. Each entry is a bare username, "username=Publisher Name" or
+// "username=designer:Designer Name", with names as credited on BGG. File holds one entry per
+// line; lines starting with # are ignored.
+type OfficialAccountsConfig struct {
+	Accounts []string
+	File     string
+}
+
+var (
+	officialAccountsMu sync.RWMutex
+	officialAccounts   = map[string]officialAccount{}
+)
+
+// ConfigureOfficialAccounts replaces the configured official accounts.
+func ConfigureOfficialAccounts(cfg OfficialAccountsConfig) error {
+	entries := cfg.Accounts
+	if cfg.File != "" {
+		f, err := os.Open(cfg.File)
+		if err != nil {
+			return fmt.Errorf("reading official accounts file: %w", err)
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			entries = append(entries, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("reading official accounts file: %w", err)
+		}
+	}
+
+	accounts := map[string]officialAccount{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		username, credit, _ := strings.Cut(entry, "=")
+		var account officialAccount
+		credit = strings.TrimSpace(credit)
+		if name, ok := strings.CutPrefix(credit, "designer:"); ok {
+			account.Designer = true
+			credit = strings.TrimSpace(name)
+		}
+		account.Credit = credit
+		accounts[strings.ToLower(strings.TrimSpace(username))] = account
+	}
+
+	officialAccountsMu.Lock()
+	officialAccounts = accounts
+	officialAccountsMu.Unlock()
+	return nil
+}
+
+func knownOfficialAccounts() map[string]officialAccount {
+	officialAccountsMu.RLock()
+	defer officialAccountsMu.RUnlock()
+	return officialAccounts
+}
+
+// officialAccount is a configured account and the designer or publisher credit it speaks for.
+// An empty Credit means the account is official for every game.
+type officialAccount struct {
+	Designer bool
+	Credit   string
+}
+
+// role describes the account, e.g. "designer" or "publisher: Lookout Games".
+func (a officialAccount) role() string {
+	switch {
+	case a.Designer:
+		return "designer"
+	case a.Credit != "":
+		return "publisher: " + a.Credit
+	default:
+		return "publisher"
+	}
+}
+
+// officialAuthors identifies posts written by a game's designer or publisher staff.
+type officialAuthors struct {
+	accounts  map[string]string // lower-case username -> role description
+	credited  map[string]string // credited name reduced by accountKey -> role description
+	gameKnown bool
+}
+
+// newOfficialAuthors builds the detector for a game. A poster is official when their username
+// spells one of the game's credited designers or publishers ("UweRosenberg" for Uwe
+// Rosenberg), or when they are a configured account. Posts are matched on the poster's
+// username only, never on display names, which anyone can choose. game may be nil when the
+// game is unknown; then only the configured accounts are recognised. When game is known,
+// configured accounts tied to a credit only count if the game has that designer or publisher.
+func newOfficialAuthors(game *thing.Item) *officialAuthors {
+	oa := &officialAuthors{accounts: map[string]string{}, credited: map[string]string{}, gameKnown: game != nil}
+	if game != nil {
+		for _, link := range game.Links {
+			switch link.Type {
+			case "boardgamedesigner":
+				oa.credited[accountKey(link.Value)] = "designer"
+			case "boardgamepublisher":
+				oa.credited[accountKey(link.Value)] = "publisher: " + link.Value
+			}
+		}
+		// "(Uncredited)" and "(Unknown)" are placeholders, not people.
+		delete(oa.credited, "uncredited")
+		delete(oa.credited, "unknown")
+		delete(oa.credited, "")
+	}
+	for username, account := range knownOfficialAccounts() {
+		if game == nil || account.Credit == "" || gameCredits(game, account) {
+			oa.accounts[username] = account.role()
+		}
+	}
+	return oa
+}
+
+func gameCredits(game *thing.Item, account officialAccount) bool {
+	linkType := "boardgamepublisher"
+	if account.Designer {
+		linkType = "boardgamedesigner"
+	}
+	for _, link := range game.Links {
+		if link.Type == linkType && strings.EqualFold(link.Value, account.Credit) {
+			return true
+		}
+	}
+	return false
+}
+
+// Role returns the official role of username, or an empty string for community posts.
+func (oa *officialAuthors) Role(username string) string {
+	if role := oa.accounts[strings.ToLower(strings.TrimSpace(username))]; role != "" {
+		return role
+	}
+	return oa.credited[accountKey(username)]
+}
+
+// Note explains why no post can be flagged official, or is empty when some account can be.
+func (oa *officialAuthors) Note() string {
+	if len(oa.accounts) > 0 || len(oa.credited) > 0 {
+		return ""
+	}
+	if !oa.gameKnown {
+		return "No official accounts are known, so no post can be flagged official: pass game_id to match posters against the game's credited designers and publishers, or configure official accounts."
+	}
+	return "The game credits no designer or publisher and no official accounts are configured for it, so no post can be flagged official."
+}
+
+// accountKey reduces a username or credited name to lower-case letters and digits, so the
+// credit "Uwe Rosenberg" matches the username "UweRosenberg".
+func accountKey(name string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
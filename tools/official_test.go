@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/kkjdaniel/gogeek/thing"
+)
+
+func TestOfficialAuthorsMatchCreditsAndConfiguredAccounts(t *testing.T) {
+	var game thing.Item
+	if err := xml.Unmarshal([]byte(`<item type="boardgame" id="31260">
+		<link type="boardgamedesigner" id="10" value="Uwe Rosenberg"/>
+		<link type="boardgamepublisher" id="20" value="Lookout Games"/>
+		<link type="boardgameartist" id="30" value="Klemens Franz"/>
+	</item>`), &game); err != nil {
+		t.Fatal(err)
+	}
+	if err := ConfigureOfficialAccounts(OfficialAccountsConfig{Accounts: []string{
+		"lookout_anna=Lookout Games",
+		"zman_bob=Z-Man Games",
+		"bgg_admin",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ConfigureOfficialAccounts(OfficialAccountsConfig{}) })
+
+	official := newOfficialAuthors(&game)
+	tests := []struct {
+		username string
+		want     string
+	}{
+		{"UweRosenberg", "designer"},
+		{"lookoutgames", "publisher: Lookout Games"},
+		{"Lookout_Anna", "publisher: Lookout Games"},
+		{"bgg_admin", "publisher"},
+		{"zman_bob", ""},
+		{"KlemensFranz", ""},
+		{"uwe_fan", ""},
+	}
+	for _, tt := range tests {
+		if got := official.Role(tt.username); got != tt.want {
+			t.Errorf("Role(%q) = %q, want %q", tt.username, got, tt.want)
+		}
+	}
+	if note := official.Note(); note != "" {
+		t.Errorf("Note() = %q, want none when accounts are known", note)
+	}
+
+	_ = ConfigureOfficialAccounts(OfficialAccountsConfig{})
+	if note := newOfficialAuthors(nil).Note(); note == "" {
+		t.Error("no note when no official account could be matched")
+	}
+}
@@ -2,80 +2,416 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
+	"strconv"
 	"strings"
 
+	"github.com/kkjdanie/bgg-mcp/tracing"
+	"github.com/kkjdaniel/gogeek/collection"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
+// flexFloat accepts prices sent either as JSON numbers or as numeric strings.
+type flexFloat float64
+
+func (f *flexFloat) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		*f = 0
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid price %s", b)
+	}
+	*f = flexFloat(v)
+	return nil
+}
+
+// flexString accepts IDs sent either as JSON strings or as numbers.
+type flexString string
+
+func (s *flexString) UnmarshalJSON(b []byte) error {
+	*s = flexString(strings.Trim(string(b), `"`))
+	return nil
+}
+
+// flexStock accepts stock sent as a boolean or as a "Y"/"N" flag.
+type flexStock bool
+
+func (s *flexStock) UnmarshalJSON(b []byte) error {
+	switch strings.ToLower(strings.Trim(string(b), `"`)) {
+	case "true", "y", "yes", "1", "instock", "in stock":
+		*s = true
+	default:
+		*s = false
+	}
+	return nil
+}
+
+type PriceResult struct {
+	Currency    string       `json:"currency"`
+	Destination string       `json:"destination"`
+	Games       []GamePrices `json:"games"`
+	// RatesDate is the date of the exchange rates used when any offer was converted.
+	RatesDate string   `json:"rates_date,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+	// Unmatched lists names that matched no BGG game.
+	Unmatched []string `json:"unmatched,omitempty"`
+}
+
+type GamePrices struct {
+	// Query is the name the game was looked up by, when it was given by name.
+	Query      string       `json:"query,omitempty"`
+	GameID     int          `json:"game_id"`
+	Name       string       `json:"name"`
+	URL        string       `json:"url,omitempty"`
+	OfferCount int          `json:"offer_count"`
+	InStock    int          `json:"in_stock"`
+	BestOffer  *PriceOffer  `json:"best_offer,omitempty"`
+	Offers     []PriceOffer `json:"offers,omitempty"`
+}
+
+// PriceOffer is one retailer's price for a game, found by the price provider named in Source.
+// Total includes shipping when the retailer reports it, which ShippingKnown records.
+type PriceOffer struct {
+	Source        string  `json:"source"`
+	Store         string  `json:"store"`
+	Country       string  `json:"country,omitempty"`
+	Price         float64 `json:"price"`
+	Shipping      float64 `json:"shipping"`
+	ShippingKnown bool    `json:"shipping_known"`
+	Total         float64 `json:"total"`
+	InStock       bool    `json:"in_stock"`
+	Link          string  `json:"link,omitempty"`
+	// OriginalCurrency is the currency the retailer listed the price in, when it was converted.
+	OriginalCurrency string `json:"original_currency,omitempty"`
+}
+
+// PriceQuery holds the options shared by bgg-price and the /v1/bgg/price REST route.
+type PriceQuery struct {
+	IDs         []int
+	Currency    string
+	Destination string
+	InStockOnly bool
+	Summary     bool
+	// Names are game names already known to the caller, used when no provider gives one.
+	Names map[int]string
+}
+
+// PriceTargets are the ways of choosing the games to price: BGG IDs, names, or a user's
+// collection filtered by status. They can be combined.
+type PriceTargets struct {
+	IDs        string
+	Names      []string
+	Username   string
+	Collection string
+}
+
+// maxPriceGames caps the games priced in one lookup.
+const maxPriceGames = 200
+
+// priceCollectionFilters are the collection statuses bgg-price can select games by.
+var priceCollectionFilters = []string{"owned", "wishlist", "fortrade", "wanttobuy", "preordered", "wanttoplay"}
+
 func PriceTool() (mcp.Tool, server.ToolHandlerFunc) {
 	tool := mcp.NewTool("bgg-price",
-		mcp.WithDescription("Get current prices for board games from multiple retailers using BGG IDs"),
+		mcp.WithDescription("Get current prices for board games from multiple retailers by BGG IDs, game names, or a user's collection (e.g. their wishlist or for-trade games), merged from every configured price source. Each game includes its best offer: the lowest total including shipping, preferring offers in stock and then offers whose shipping cost is known."),
 		mcp.WithString("ids",
-			mcp.Required(),
 			mcp.Description("Comma-separated BGG IDs (e.g., '12,844,2096,13857')"),
 		),
+		mcp.WithArray("names",
+			mcp.Description("Game names to price, each matched to its best BGG search result (e.g., ['Wingspan', 'Ark Nova'])"),
+		),
+		mcp.WithString("username",
+			mcp.Description("Price the games in this BGG user's collection. When the user refers to themselves (me, my, I), use 'SELF' as the value."),
+		),
+		mcp.WithString("collection",
+			mcp.Enum(priceCollectionFilters...),
+			mcp.Description("Which part of the user's collection to price (default: owned)"),
+		),
 		mcp.WithString("currency",
-			mcp.Description("Currency code: DKK, GBP, SEK, EUR, or USD (default: USD)"),
+			mcp.Description(fmt.Sprintf("ISO 4217 currency code, e.g. USD, EUR, GBP, CAD or AUD; prices in other currencies are converted (default: %s)", CurrentSettings().Currency)),
 		),
 		mcp.WithString("destination",
-			mcp.Description("Destination country: DK, SE, GB, DE, or US (default: US)"),
+			mcp.Description(fmt.Sprintf("Two-letter destination country for shipping, e.g. US, GB, DE, CA or AU (default: %s)", CurrentSettings().Destination)),
+		),
+		mcp.WithBoolean("in_stock",
+			mcp.Description("Only include offers that are in stock (default: false)"),
+		),
+		mcp.WithBoolean("summary",
+			mcp.Description("Return only the best offer and offer counts per game instead of every offer (default: true when pricing a collection, otherwise false)"),
 		),
+		withFormatArgument(FormatJSON, FormatCSV, FormatMarkdown),
 	)
 
 	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		arguments := request.GetArguments()
 
-		ids, hasIDs := arguments["ids"].(string)
-		if !hasIDs || ids == "" {
-			return mcp.NewToolResultText("IDs parameter is required"), nil
+		targets := PriceTargets{Username: stringArg(arguments["username"])}
+		targets.IDs, _ = arguments["ids"].(string)
+		targets.Collection, _ = arguments["collection"].(string)
+		switch names := arguments["names"].(type) {
+		case []interface{}:
+			for _, n := range names {
+				if name, ok := n.(string); ok {
+					targets.Names = append(targets.Names, name)
+				}
+			}
+		case string:
+			targets.Names = []string{names}
+		}
+
+		query := PriceQuery{
+			Currency:    CurrentSettings().Currency,
+			Destination: CurrentSettings().Destination,
+			Summary:     targets.Username != "",
+		}
+		if c, ok := arguments["currency"].(string); ok && c != "" {
+			query.Currency = strings.ToUpper(c)
+		}
+		if d, ok := arguments["destination"].(string); ok && d != "" {
+			query.Destination = strings.ToUpper(d)
+		}
+		query.InStockOnly, _ = arguments["in_stock"].(bool)
+		if summary, ok := arguments["summary"].(bool); ok {
+			query.Summary = summary
 		}
 
-		currency := "USD"
-		if c, ok := arguments["currency"].(string); ok {
-			currency = strings.ToUpper(c)
+		result, err := lookupPrices(ctx, targets, query)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
 		}
 
-		destination := "US"
-		if d, ok := arguments["destination"].(string); ok {
-			destination = strings.ToUpper(d)
+		return formatToolResult(formatArgument(arguments), result, tableFor(func() table { return priceTable(result) }))
+	}
+
+	return tool, handler
+}
+
+// parseIDList parses a comma-separated list of BGG IDs.
+func parseIDList(s string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
 		}
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ID format: %s", part)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("At least one ID is required")
+	}
+	return ids, nil
+}
 
-		baseURL := "https://boardgameprices.co.uk/api/info"
-		params := url.Values{}
-		params.Add("eid", ids)
-		params.Add("currency", currency)
-		params.Add("destination", destination)
-		params.Add("sitename", "bgg-mcp")
+// lookupPrices resolves targets to BGG IDs and fetches their prices. Games named more than
+// once, or both named and in the collection, are priced once.
+func lookupPrices(ctx context.Context, targets PriceTargets, query PriceQuery) (*PriceResult, error) {
+	if targets.IDs == "" && len(targets.Names) == 0 && targets.Username == "" {
+		return nil, fmt.Errorf("ids, names or username is required")
+	}
+
+	seen := map[int]bool{}
+	add := func(id int) {
+		if !seen[id] {
+			seen[id] = true
+			query.IDs = append(query.IDs, id)
+		}
+	}
+	if query.Names == nil {
+		query.Names = map[int]string{}
+	}
 
-		resp, err := http.Get(baseURL + "?" + params.Encode())
+	if targets.IDs != "" {
+		ids, err := parseIDList(targets.IDs)
 		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("API request error: %v", err)), nil
+			return nil, err
+		}
+		for _, id := range ids {
+			add(id)
 		}
-		defer resp.Body.Close()
+	}
 
-		body, err := io.ReadAll(resp.Body)
+	queries := map[int]string{}
+	var unmatched []string
+	for _, name := range targets.Names {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		match, err := findBestGameMatch(ctx, name)
 		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("Error reading response: %v", err)), nil
+			unmatched = append(unmatched, name)
+			continue
 		}
+		queries[match.ID] = name
+		query.Names[match.ID] = match.Name.Value
+		add(match.ID)
+	}
 
-		var result interface{}
-		if err := json.Unmarshal(body, &result); err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("JSON parsing error: %v", err)), nil
+	if targets.Username != "" {
+		username, err := resolveUsername(ctx, targets.Username)
+		if err != nil {
+			return nil, err
+		}
+		// The filter becomes an argument key of buildCollectionOptions, which are lower case.
+		filter := strings.ToLower(strings.TrimSpace(targets.Collection))
+		if filter == "" {
+			filter = "owned"
+		}
+		if !containsFold(priceCollectionFilters, filter) {
+			return nil, fmt.Errorf("collection must be one of: %s", strings.Join(priceCollectionFilters, ", "))
 		}
 
-		out, err := json.Marshal(result)
+		done := traceBGG(ctx, "collection.Query", tracing.Username("bgg.username", username), tracing.Bool("bgg."+filter, true))
+		result, err := collection.Query(username, buildCollectionOptions(map[string]interface{}{filter: true})...)
+		done(err)
 		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("JSON encoding error: %v", err)), nil
+			return nil, fmt.Errorf("error fetching collection: %v", err)
 		}
+		for _, item := range result.Items {
+			if _, ok := query.Names[item.ObjectID]; !ok {
+				query.Names[item.ObjectID] = item.Name
+			}
+			add(item.ObjectID)
+		}
+	}
 
-		return mcp.NewToolResultText(string(out)), nil
+	if len(query.IDs) == 0 && len(unmatched) == 0 {
+		return nil, fmt.Errorf("no games to price")
+	}
+	if len(query.IDs) > maxPriceGames {
+		return nil, fmt.Errorf("too many games (%d); at most %d can be priced at once", len(query.IDs), maxPriceGames)
 	}
 
-	return tool, handler
+	result := &PriceResult{Currency: query.Currency, Destination: query.Destination, Games: []GamePrices{}}
+	if len(query.IDs) > 0 {
+		var err error
+		if result, err = fetchPrices(ctx, query); err != nil {
+			return nil, err
+		}
+	}
+	for i := range result.Games {
+		result.Games[i].Query = queries[result.Games[i].GameID]
+	}
+	result.Unmatched = unmatched
+	return result, nil
+}
+
+// fetchPrices looks up retailer prices for query.IDs from every price provider and picks the
+// best offer per game. Games are returned in the order requested, including those with no
+// offers; names missing from the price data are filled in from BGG.
+func fetchPrices(ctx context.Context, query PriceQuery) (*PriceResult, error) {
+	if !knownCurrency(query.Currency) && !boardGamePricesCurrencies[query.Currency] {
+		return nil, fmt.Errorf("no exchange rate for %s", query.Currency)
+	}
+
+	byID, warnings, err := queryPriceProviders(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PriceResult{Currency: query.Currency, Destination: query.Destination, Games: []GamePrices{}, Warnings: warnings}
+	var unnamed []int
+	for _, id := range query.IDs {
+		game, ok := byID[id]
+		if !ok {
+			game = &GamePrices{GameID: id}
+		}
+		if query.InStockOnly {
+			var inStock []PriceOffer
+			for _, o := range game.Offers {
+				if o.InStock {
+					inStock = append(inStock, o)
+				}
+			}
+			game.Offers = inStock
+		}
+		game.OfferCount = len(game.Offers)
+		for _, o := range game.Offers {
+			if o.InStock {
+				game.InStock++
+			}
+			if o.OriginalCurrency != "" {
+				result.RatesDate = fxRatesDate()
+			}
+		}
+		game.BestOffer = bestOffer(game.Offers)
+		if query.Summary {
+			game.Offers = nil
+		}
+		if game.Name == "" {
+			game.Name = query.Names[id]
+		}
+		if game.Name == "" {
+			unnamed = append(unnamed, id)
+		}
+		result.Games = append(result.Games, *game)
+	}
+
+	if len(unnamed) > 0 {
+		if items, err := fetchThings(ctx, unnamed); err == nil {
+			names := map[int]string{}
+			for _, item := range items {
+				names[item.ID] = extractEssentialInfo(item).Name
+			}
+			for i := range result.Games {
+				if result.Games[i].Name == "" {
+					result.Games[i].Name = names[result.Games[i].GameID]
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// bestOffer returns the cheapest offer by total, preferring offers in stock. An offer with
+// unknown shipping has a total of just its price, so it only wins when no offer with known
+// shipping is left to compare against.
+func bestOffer(offers []PriceOffer) *PriceOffer {
+	var best *PriceOffer
+	for i := range offers {
+		o := &offers[i]
+		switch {
+		case best == nil:
+			best = o
+		case o.InStock != best.InStock:
+			if o.InStock {
+				best = o
+			}
+		case o.ShippingKnown != best.ShippingKnown:
+			if o.ShippingKnown {
+				best = o
+			}
+		case o.Total < best.Total:
+			best = o
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	b := *best
+	return &b
+}
+
+func priceTable(result *PriceResult) table {
+	t := table{Headers: []string{"ID", "Name", "Offers", "In Stock", "Best Store", "Price", "Shipping", "Total", "Currency", "Link"}}
+	for _, g := range result.Games {
+		row := []string{strconv.Itoa(g.GameID), g.Name, strconv.Itoa(g.OfferCount), strconv.Itoa(g.InStock), "", "", "", "", result.Currency, ""}
+		if b := g.BestOffer; b != nil {
+			shipping := "unknown"
+			if b.ShippingKnown {
+				shipping = strconv.FormatFloat(b.Shipping, 'f', 2, 64)
+			}
+			row[4], row[5], row[6], row[7], row[9] = b.Store, formatDecimal(b.Price), shipping, formatDecimal(b.Total), b.Link
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t
 }
@@ -0,0 +1,447 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PriceProvider is a source of retailer prices. Offers are returned in query.Currency; a
+// provider that can't price in that currency converts with the exchange rate table and sets
+// OriginalCurrency. Timeout bounds a whole Prices call, however many requests it makes.
+type PriceProvider interface {
+	Name() string
+	Timeout() time.Duration
+	Prices(ctx context.Context, query PriceQuery) ([]ProviderPrice, error)
+}
+
+// ProviderPrice is one offer for a game from a PriceProvider.
+type ProviderPrice struct {
+	GameID int
+	Name   string
+	URL    string
+	Offer  PriceOffer
+}
+
+// defaultPriceProviderTimeout bounds providers configured without a timeout of their own.
+const defaultPriceProviderTimeout = 15 * time.Second
+
+var (
+	priceProvidersMu sync.RWMutex
+	priceProviders   = []PriceProvider{NewBoardGamePricesProvider(0)}
+)
+
+// ConfigurePriceProviders replaces the providers bgg-price queries. With none configured,
+// prices come from boardgameprices.co.uk.
+func ConfigurePriceProviders(providers []PriceProvider) {
+	if len(providers) == 0 {
+		providers = []PriceProvider{NewBoardGamePricesProvider(0)}
+	}
+	priceProvidersMu.Lock()
+	priceProviders = providers
+	priceProvidersMu.Unlock()
+}
+
+func currentPriceProviders() []PriceProvider {
+	priceProvidersMu.RLock()
+	defer priceProvidersMu.RUnlock()
+	return priceProviders
+}
+
+// providerTimeout returns the deadline for one provider's Prices call.
+func providerTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return defaultPriceProviderTimeout
+	}
+	return timeout
+}
+
+// boardGamePricesProvider reads prices from boardgameprices.co.uk.
+type boardGamePricesProvider struct {
+	timeout time.Duration
+}
+
+// NewBoardGamePricesProvider returns the boardgameprices.co.uk provider. A zero timeout uses
+// the default.
+func NewBoardGamePricesProvider(timeout time.Duration) PriceProvider {
+	return &boardGamePricesProvider{timeout: providerTimeout(timeout)}
+}
+
+// boardGamePricesCurrencies and boardGamePricesDestinations are the currencies and shipping
+// destinations boardgameprices.co.uk quotes for.
+var (
+	boardGamePricesCurrencies   = map[string]bool{"DKK": true, "GBP": true, "SEK": true, "EUR": true, "USD": true}
+	boardGamePricesDestinations = map[string]bool{"DK": true, "SE": true, "GB": true, "DE": true, "US": true}
+)
+
+// boardGamePricesBatchSize is the number of games asked for per boardgameprices.co.uk request.
+const boardGamePricesBatchSize = 20
+
+// priceInfoResponse is the boardgameprices.co.uk /api/info response.
+type priceInfoResponse struct {
+	Items []priceInfoItem `json:"items"`
+}
+
+type priceInfoItem struct {
+	ExternalID flexString `json:"external_id"`
+	Name       string     `json:"name"`
+	URL        string     `json:"url"`
+	Prices     []struct {
+		Store    string     `json:"store"`
+		Country  string     `json:"country"`
+		Price    flexFloat  `json:"price"`
+		Shipping *flexFloat `json:"shipping"`
+		Stock    flexStock  `json:"stock"`
+		Link     string     `json:"link"`
+	} `json:"prices"`
+}
+
+func (p *boardGamePricesProvider) Name() string { return "boardgameprices" }
+
+func (p *boardGamePricesProvider) Timeout() time.Duration { return p.timeout }
+
+// Prices queries boardgameprices.co.uk in batches. Other currencies are fetched in USD and
+// converted. For destinations it doesn't quote, prices are fetched for the US and shipping is
+// reported as unknown.
+func (p *boardGamePricesProvider) Prices(ctx context.Context, query PriceQuery) ([]ProviderPrice, error) {
+	currency, destination := query.Currency, query.Destination
+	if !boardGamePricesCurrencies[currency] {
+		currency = "USD"
+	}
+	quotesShipping := boardGamePricesDestinations[destination]
+	if !quotesShipping {
+		destination = "US"
+	}
+
+	var items []priceInfoItem
+	for i := 0; i < len(query.IDs); i += boardGamePricesBatchSize {
+		end := i + boardGamePricesBatchSize
+		if end > len(query.IDs) {
+			end = len(query.IDs)
+		}
+		batch, err := p.fetch(ctx, query.IDs[i:end], currency, destination)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, batch...)
+	}
+
+	var prices []ProviderPrice
+	for _, item := range items {
+		id, err := strconv.Atoi(string(item.ExternalID))
+		if err != nil {
+			continue
+		}
+		for _, price := range item.Prices {
+			offer := PriceOffer{
+				Store:   price.Store,
+				Country: price.Country,
+				InStock: bool(price.Stock),
+				Link:    price.Link,
+			}
+			if currency != query.Currency {
+				offer.OriginalCurrency = currency
+			}
+			if offer.Price, err = convertCurrency(float64(price.Price), currency, query.Currency); err != nil {
+				return nil, err
+			}
+			if price.Shipping != nil && quotesShipping {
+				if offer.Shipping, err = convertCurrency(float64(*price.Shipping), currency, query.Currency); err != nil {
+					return nil, err
+				}
+				offer.ShippingKnown = true
+			}
+			prices = append(prices, ProviderPrice{GameID: id, Name: html.UnescapeString(item.Name), URL: item.URL, Offer: offer})
+		}
+	}
+	return prices, nil
+}
+
+func (p *boardGamePricesProvider) fetch(ctx context.Context, ids []int, currency, destination string) ([]priceInfoItem, error) {
+	strIDs := make([]string, len(ids))
+	for i, id := range ids {
+		strIDs[i] = strconv.Itoa(id)
+	}
+	params := url.Values{}
+	params.Add("eid", strings.Join(strIDs, ","))
+	params.Add("currency", currency)
+	params.Add("destination", destination)
+	params.Add("sitename", "bgg-mcp")
+
+	var resp priceInfoResponse
+	if err := fetchJSON(ctx, "https://boardgameprices.co.uk/api/info?"+params.Encode(), &resp); err != nil {
+		return nil, err
+	}
+	return resp.Items, nil
+}
+
+// FeedProviderConfig describes a price list maintained outside bgg-mcp, such as a local
+// retailer's export or a hand-kept file.
+type FeedProviderConfig struct {
+	Name string
+	// Source is an http(s) URL or a local file path. It is read on every lookup, so a manual
+	// price file can be edited while the server runs.
+	Source string
+	// Format is "json" or "csv"; empty picks it from the source's extension.
+	Format string
+	// Currency and Country apply to entries that don't give their own.
+	Currency string
+	Country  string
+	// ShipsTo lists the destinations the feed's retailers deliver to; empty means anywhere.
+	ShipsTo []string
+	Timeout time.Duration
+}
+
+// feedEntry is one row of a price feed. JSON feeds are an array of these objects, or an
+// object with an "items" array; CSV feeds use the JSON names as column headers. Entries
+// without in_stock are taken to be in stock.
+type feedEntry struct {
+	BGGID    flexString `json:"bgg_id"`
+	Name     string     `json:"name"`
+	Store    string     `json:"store"`
+	Price    flexFloat  `json:"price"`
+	Shipping *flexFloat `json:"shipping"`
+	Currency string     `json:"currency"`
+	Country  string     `json:"country"`
+	InStock  *flexStock `json:"in_stock"`
+	URL      string     `json:"url"`
+}
+
+type feedPriceProvider struct {
+	cfg FeedProviderConfig
+}
+
+// NewFeedPriceProvider returns a provider reading a JSON or CSV price feed.
+func NewFeedPriceProvider(cfg FeedProviderConfig) PriceProvider {
+	cfg.Timeout = providerTimeout(cfg.Timeout)
+	cfg.Currency = strings.ToUpper(cfg.Currency)
+	if cfg.Format == "" {
+		cfg.Format = "json"
+		if strings.EqualFold(filepath.Ext(cfg.Source), ".csv") {
+			cfg.Format = "csv"
+		}
+	}
+	return &feedPriceProvider{cfg: cfg}
+}
+
+func (p *feedPriceProvider) Name() string { return p.cfg.Name }
+
+func (p *feedPriceProvider) Timeout() time.Duration { return p.cfg.Timeout }
+
+func (p *feedPriceProvider) Prices(ctx context.Context, query PriceQuery) ([]ProviderPrice, error) {
+	if len(p.cfg.ShipsTo) > 0 && !containsFold(p.cfg.ShipsTo, query.Destination) {
+		return nil, nil
+	}
+
+	data, err := p.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseFeed(data, p.cfg.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := map[int]bool{}
+	for _, id := range query.IDs {
+		wanted[id] = true
+	}
+
+	var prices []ProviderPrice
+	for _, e := range entries {
+		id, err := strconv.Atoi(strings.TrimSpace(string(e.BGGID)))
+		if err != nil || !wanted[id] {
+			continue
+		}
+		currency := p.cfg.Currency
+		if e.Currency != "" {
+			currency = strings.ToUpper(e.Currency)
+		}
+		offer := PriceOffer{Store: e.Store, Country: e.Country, InStock: e.InStock == nil || bool(*e.InStock), Link: e.URL}
+		if offer.Store == "" {
+			offer.Store = p.cfg.Name
+		}
+		if offer.Country == "" {
+			offer.Country = p.cfg.Country
+		}
+		if currency != query.Currency {
+			offer.OriginalCurrency = currency
+		}
+		if offer.Price, err = convertCurrency(float64(e.Price), currency, query.Currency); err != nil {
+			return nil, err
+		}
+		if e.Shipping != nil {
+			if offer.Shipping, err = convertCurrency(float64(*e.Shipping), currency, query.Currency); err != nil {
+				return nil, err
+			}
+			offer.ShippingKnown = true
+		}
+		prices = append(prices, ProviderPrice{GameID: id, Name: e.Name, Offer: offer})
+	}
+	return prices, nil
+}
+
+func (p *feedPriceProvider) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(p.cfg.Source, "http://") && !strings.HasPrefix(p.cfg.Source, "https://") {
+		return os.ReadFile(p.cfg.Source)
+	}
+	resp, err := httpGet(ctx, p.cfg.Source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d", resp.Request.URL.Host, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func parseFeed(data []byte, format string) ([]feedEntry, error) {
+	if format == "csv" {
+		return parseCSVFeed(data)
+	}
+
+	var entries []feedEntry
+	if err := json.Unmarshal(data, &entries); err == nil {
+		return entries, nil
+	}
+	var wrapped struct {
+		Items []feedEntry `json:"items"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return nil, fmt.Errorf("error parsing price feed: %w", err)
+	}
+	return wrapped.Items, nil
+}
+
+// parseCSVFeed converts CSV rows to feed entries by way of JSON, so both formats share the
+// same field names and value parsing.
+func parseCSVFeed(data []byte) ([]feedEntry, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing price feed: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	headers := rows[0]
+	for i := range headers {
+		headers[i] = strings.ToLower(strings.TrimSpace(headers[i]))
+	}
+	var entries []feedEntry
+	for _, row := range rows[1:] {
+		obj := map[string]string{}
+		for i, value := range row {
+			if i < len(headers) && strings.TrimSpace(value) != "" {
+				obj[headers[i]] = strings.TrimSpace(value)
+			}
+		}
+		raw, _ := json.Marshal(obj)
+		var e feedEntry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return nil, fmt.Errorf("error parsing price feed row %v: %w", row, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// queryPriceProviders asks every provider concurrently, each within its timeout, and merges
+// their offers per game, dropping duplicates listed by more than one provider. Provider
+// failures are reported as warnings unless every provider failed.
+func queryPriceProviders(ctx context.Context, query PriceQuery) (map[int]*GamePrices, []string, error) {
+	providers := currentPriceProviders()
+	results := make([][]ProviderPrice, len(providers))
+	errs := make([]error, len(providers))
+
+	var wg sync.WaitGroup
+	for i, provider := range providers {
+		wg.Add(1)
+		go func(i int, provider PriceProvider) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, provider.Timeout())
+			defer cancel()
+			results[i], errs[i] = provider.Prices(ctx, query)
+		}(i, provider)
+	}
+	wg.Wait()
+
+	var warnings []string
+	for i, err := range errs {
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %v", providers[i].Name(), err))
+		}
+	}
+	if len(warnings) == len(providers) {
+		return nil, nil, fmt.Errorf("API request error: %s", strings.Join(warnings, "; "))
+	}
+
+	games := map[int]*GamePrices{}
+	seen := map[int]map[string]int{}
+	for i, prices := range results {
+		for _, p := range prices {
+			game, ok := games[p.GameID]
+			if !ok {
+				game = &GamePrices{GameID: p.GameID}
+				games[p.GameID] = game
+				seen[p.GameID] = map[string]int{}
+			}
+			if game.Name == "" {
+				game.Name = p.Name
+			}
+			if game.URL == "" {
+				game.URL = p.URL
+			}
+
+			offer := p.Offer
+			offer.Source = providers[i].Name()
+			offer.Total = math.Round((offer.Price+offer.Shipping)*100) / 100
+
+			key := offerKey(offer)
+			if j, dup := seen[p.GameID][key]; dup {
+				if offer.Total < game.Offers[j].Total {
+					game.Offers[j] = offer
+				}
+				continue
+			}
+			seen[p.GameID][key] = len(game.Offers)
+			game.Offers = append(game.Offers, offer)
+		}
+	}
+	return games, warnings, nil
+}
+
+// offerKey identifies the same retailer listing reported by several providers: the store and
+// link, or the store and price when there is no link.
+func offerKey(o PriceOffer) string {
+	store := strings.ToLower(strings.TrimSpace(o.Store))
+	if o.Link != "" {
+		return store + "|" + strings.TrimSuffix(strings.ToLower(o.Link), "/")
+	}
+	return store + "|" + strconv.FormatFloat(o.Price, 'f', 2, 64)
+}
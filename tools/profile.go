@@ -0,0 +1,357 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// profileKind describes a BGG person, company or family and the geekdo object it maps to.
+type profileKind struct {
+	ObjectType string // "person", "company" or "family"
+	Subtype    string // link subtype, e.g. "boardgamedesigner"
+	Label      string
+}
+
+var (
+	designerProfile  = profileKind{ObjectType: "person", Subtype: "boardgamedesigner", Label: "designer"}
+	artistProfile    = profileKind{ObjectType: "person", Subtype: "boardgameartist", Label: "artist"}
+	publisherProfile = profileKind{ObjectType: "company", Subtype: "boardgamepublisher", Label: "publisher"}
+	familyProfile    = profileKind{ObjectType: "family", Subtype: "boardgamefamily", Label: "family"}
+)
+
+type ProfileResult struct {
+	ID           int              `json:"id"`
+	Name         string           `json:"name"`
+	Role         string           `json:"role"`
+	Bio          string           `json:"bio,omitempty"`
+	Website      string           `json:"website,omitempty"`
+	Link         string           `json:"link"`
+	TotalGames   int              `json:"total_games"`
+	Ludography   []LudographyItem `json:"ludography"`
+	Truncated    bool             `json:"truncated,omitempty"`
+	UpcomingOnly bool             `json:"upcoming_only,omitempty"`
+	// Note explains which games a truncated ludography lists.
+	Note string `json:"note,omitempty"`
+}
+
+type LudographyItem struct {
+	ID           int     `json:"id"`
+	Name         string  `json:"name"`
+	Year         int     `json:"year"`
+	Type         string  `json:"type,omitempty"`
+	Rank         int     `json:"rank,omitempty"`
+	BGGRating    float64 `json:"bgg_rating,omitempty"`
+	BayesAverage float64 `json:"bayes_average,omitempty"`
+	NumRatings   int     `json:"num_ratings,omitempty"`
+	Owned        *bool   `json:"owned,omitempty"`
+	Played       *bool   `json:"played,omitempty"`
+	NumPlays     int     `json:"num_plays,omitempty"`
+}
+
+type geekdoSearchResponse struct {
+	Items []struct {
+		ObjectID string `json:"objectid"`
+		Name     string `json:"name"`
+	} `json:"items"`
+}
+
+type geekdoItemResponse struct {
+	Item struct {
+		ObjectID    string `json:"objectid"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Website     struct {
+			URL string `json:"url"`
+		} `json:"website"`
+	} `json:"item"`
+}
+
+type geekdoLinkedItemsResponse struct {
+	Items []struct {
+		ObjectID      string `json:"objectid"`
+		Name          string `json:"name"`
+		YearPublished string `json:"yearpublished"`
+	} `json:"items"`
+	Config struct {
+		NumItems int `json:"numitems"`
+	} `json:"config"`
+}
+
+func DesignerTool() (mcp.Tool, server.ToolHandlerFunc) {
+	tool := mcp.NewTool("bgg-designer",
+		mcp.WithDescription(fmt.Sprintf("Get a BoardGameGeek (BGG) designer or artist profile: biography plus their ludography, newest first, with years, ranks and ratings. Only the newest %d games are listed unless limit is raised; total_games always gives the full count, and truncated and note say when games were left out.", CurrentSettings().LudographyLimit)),
+		mcp.WithString("name",
+			mcp.Description("The name of the designer or artist (e.g., 'Uwe Rosenberg')"),
+		),
+		mcp.WithNumber("id",
+			mcp.Description("The BoardGameGeek person ID (preferred when known, e.g. from designer_ids in bgg-details)"),
+		),
+		mcp.WithString("role",
+			mcp.Enum("designer", "artist"),
+			mcp.Description("Whether to list games the person designed or illustrated (default: designer)"),
+		),
+		mcp.WithBoolean("upcoming",
+			mcp.Description("Only return unpublished and recently published games"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Maximum number of games in the ludography, newest first (default: %d). Raise it to total_games for the full ludography.", CurrentSettings().LudographyLimit)),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		kind := designerProfile
+		if role, ok := request.GetArguments()["role"].(string); ok && role == "artist" {
+			kind = artistProfile
+		}
+		return handleProfileRequest(ctx, kind, request.GetArguments())
+	}
+
+	return tool, handler
+}
+
+func PublisherTool() (mcp.Tool, server.ToolHandlerFunc) {
+	tool := mcp.NewTool("bgg-publisher",
+		mcp.WithDescription(fmt.Sprintf("Get a BoardGameGeek (BGG) publisher profile: description plus the games they have published, newest first, with years, ranks and ratings. Only the newest %d games are listed unless limit is raised; total_games always gives the full count, and truncated and note say when games were left out.", CurrentSettings().LudographyLimit)),
+		mcp.WithString("name",
+			mcp.Description("The name of the publisher (e.g., 'Stonemaier Games')"),
+		),
+		mcp.WithNumber("id",
+			mcp.Description("The BoardGameGeek company ID (preferred when known, e.g. from publisher_ids in bgg-details)"),
+		),
+		mcp.WithBoolean("upcoming",
+			mcp.Description("Only return unpublished and recently published games"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Maximum number of games in the ludography, newest first (default: %d). Raise it to total_games for the full ludography.", CurrentSettings().LudographyLimit)),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return handleProfileRequest(ctx, publisherProfile, request.GetArguments())
+	}
+
+	return tool, handler
+}
+
+func handleProfileRequest(ctx context.Context, kind profileKind, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	profile, err := loadProfile(ctx, kind, arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	out, err := json.Marshal(profile)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error formatting results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+// loadProfile resolves the profile named by the id or name argument and fetches it with its
+// ludography. Errors are worded for the tool result.
+func loadProfile(ctx context.Context, kind profileKind, arguments map[string]interface{}) (*ProfileResult, error) {
+	var profileID int
+	var err error
+
+	if idVal, ok := arguments["id"]; ok && idVal != nil {
+		switch v := idVal.(type) {
+		case float64:
+			profileID = int(v)
+		case string:
+			profileID, err = strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid %s ID format", kind.Label)
+			}
+		}
+	} else if name, ok := arguments["name"].(string); ok && name != "" {
+		profileID, err = findProfileID(ctx, kind, name)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to find %s: %v", kind.Label, err)
+		}
+	} else {
+		return nil, fmt.Errorf("Either 'name' or 'id' parameter is required")
+	}
+
+	limit := CurrentSettings().LudographyLimit
+	if l, ok := arguments["limit"].(float64); ok && l > 0 {
+		limit = int(l)
+	}
+	upcoming, _ := arguments["upcoming"].(bool)
+
+	profile, err := fetchProfile(ctx, kind, profileID, limit, upcoming)
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching %s profile: %v", kind.Label, err)
+	}
+	return profile, nil
+}
+
+// findProfileID resolves a person or company name using the BGG site search, preferring an
+// exact (case-insensitive) name match over the first result.
+func findProfileID(ctx context.Context, kind profileKind, name string) (int, error) {
+	params := url.Values{}
+	params.Add("q", name)
+	params.Add("showcount", "20")
+
+	var result geekdoSearchResponse
+	if err := fetchJSON(ctx, "https://boardgamegeek.com/search/"+kind.Subtype+"?"+params.Encode(), &result); err != nil {
+		return 0, fmt.Errorf("search failed: %w", err)
+	}
+	if len(result.Items) == 0 {
+		return 0, fmt.Errorf("no %s found matching '%s'", kind.Label, name)
+	}
+
+	best := result.Items[0]
+	for _, item := range result.Items {
+		if strings.EqualFold(item.Name, name) {
+			best = item
+			break
+		}
+	}
+
+	id, err := strconv.Atoi(best.ObjectID)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s ID '%s'", kind.Label, best.ObjectID)
+	}
+	return id, nil
+}
+
+func fetchProfile(ctx context.Context, kind profileKind, id, limit int, upcoming bool) (*ProfileResult, error) {
+	params := url.Values{}
+	params.Add("nosession", "1")
+	params.Add("objectid", strconv.Itoa(id))
+	params.Add("objecttype", kind.ObjectType)
+	params.Add("subtype", kind.Subtype)
+
+	var item geekdoItemResponse
+	if err := fetchJSON(ctx, "https://api.geekdo.com/api/geekitems?"+params.Encode(), &item); err != nil {
+		return nil, err
+	}
+
+	profile := &ProfileResult{
+		ID:           id,
+		Name:         item.Item.Name,
+		Role:         kind.Label,
+		Bio:          sanitizeDescription(stripHTML(item.Item.Description)),
+		Website:      item.Item.Website.URL,
+		Link:         fmt.Sprintf("https://boardgamegeek.com/%s/%d", kind.Subtype, id),
+		Ludography:   []LudographyItem{},
+		UpcomingOnly: upcoming,
+	}
+
+	ludography, total, err := fetchLudography(ctx, kind, id, limit, upcoming)
+	if err != nil {
+		return nil, err
+	}
+	profile.TotalGames = total
+	profile.Truncated = !upcoming && total > len(ludography)
+	profile.Ludography = ludography
+	if profile.Truncated {
+		profile.Note = fmt.Sprintf("Showing the %d newest of %d games; raise limit to list more.", len(ludography), total)
+	}
+
+	return profile, nil
+}
+
+// fetchLudography pages through the linked board games of a person, company or family, sorted
+// newest first, then hydrates them with ratings and ranks from the thing API.
+func fetchLudography(ctx context.Context, kind profileKind, id, limit int, upcoming bool) ([]LudographyItem, int, error) {
+	const pageSize = 50
+	currentYear := time.Now().Year()
+
+	var entries []LudographyItem
+	total := 0
+	maxPages := (limit+pageSize-1)/pageSize + 1
+
+	for page := 1; page <= maxPages && len(entries) < limit; page++ {
+		params := url.Values{}
+		params.Add("ajax", "1")
+		params.Add("linkdata_index", "boardgame")
+		params.Add("nosession", "1")
+		params.Add("objectid", strconv.Itoa(id))
+		params.Add("objecttype", kind.ObjectType)
+		params.Add("subtype", kind.Subtype)
+		params.Add("pageid", strconv.Itoa(page))
+		params.Add("showcount", strconv.Itoa(pageSize))
+		params.Add("sort", "yearpublished")
+
+		var linked geekdoLinkedItemsResponse
+		if err := fetchJSON(ctx, "https://api.geekdo.com/api/geekitem/linkeditems?"+params.Encode(), &linked); err != nil {
+			return nil, 0, err
+		}
+		if page == 1 {
+			total = linked.Config.NumItems
+		}
+
+		reachedOlder := false
+		for _, li := range linked.Items {
+			gameID, err := strconv.Atoi(li.ObjectID)
+			if err != nil {
+				continue
+			}
+			year, _ := strconv.Atoi(li.YearPublished)
+			if upcoming && year != 0 && year < currentYear-1 {
+				reachedOlder = true
+				continue
+			}
+			entries = append(entries, LudographyItem{ID: gameID, Name: li.Name, Year: year})
+			if len(entries) >= limit {
+				break
+			}
+		}
+
+		if len(linked.Items) < pageSize || reachedOlder {
+			break
+		}
+	}
+
+	if len(entries) == 0 {
+		return []LudographyItem{}, total, nil
+	}
+
+	ids := make([]int, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+	}
+	things, err := fetchThings(ctx, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	byID := make(map[int]int, len(entries))
+	for i, e := range entries {
+		byID[e.ID] = i
+	}
+	for _, item := range things {
+		i, ok := byID[item.ID]
+		if !ok {
+			continue
+		}
+		entries[i].Type = item.Type
+		entries[i].Rank = boardGameRank(item)
+		if item.Statistics != nil {
+			entries[i].BGGRating = item.Statistics.Average.Value
+			entries[i].BayesAverage = item.Statistics.BayesAverage.Value
+			entries[i].NumRatings = item.Statistics.UsersRated.Value
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		// Unpublished titles (year 0) are listed first as the most upcoming.
+		yi, yj := entries[i].Year, entries[j].Year
+		if yi == 0 || yj == 0 {
+			return yi == 0 && yj != 0
+		}
+		return yi > yj
+	})
+
+	return entries, total, nil
+}
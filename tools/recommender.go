@@ -5,9 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"strconv"
 
+	"github.com/kkjdanie/bgg-mcp/tracing"
 	"github.com/kkjdaniel/gogeek/thing"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -31,7 +31,7 @@ func RecommenderTool() (mcp.Tool, server.ToolHandlerFunc) {
 			mcp.Description("BoardGameGeek (BGG) ID of the game to base recommendations on (preferred for speed)"),
 		),
 		mcp.WithNumber("min_votes",
-			mcp.Description("Minimum votes threshold for recommendation quality (default: 30)"),
+			mcp.Description(fmt.Sprintf("Minimum votes threshold for recommendation quality (default: %d)", CurrentSettings().RecommendationMinVotes)),
 		),
 	)
 
@@ -42,48 +42,48 @@ func RecommenderTool() (mcp.Tool, server.ToolHandlerFunc) {
 		var err error
 
 		if nameVal, ok := arguments["name"].(string); ok && nameVal != "" {
-			gameDetails, err := searchAndSortGames(nameVal, "boardgame", 1)
+			gameDetails, err := searchAndSortGames(ctx, nameVal, "boardgame", 1)
 			if err != nil {
-				return mcp.NewToolResultText(fmt.Sprintf("Error finding game by name: %v", err)), nil
+				return mcp.NewToolResultError(fmt.Sprintf("Error finding game by name: %v", err)), nil
 			}
 			if len(gameDetails.Items) == 0 {
-				return mcp.NewToolResultText("No games found with that name"), nil
+				return mcp.NewToolResultError("No games found with that name"), nil
 			}
 			gameID = gameDetails.Items[0].ID
 		} else if idVal, ok := arguments["id"].(string); ok && idVal != "" {
 			gameID, err = strconv.Atoi(idVal)
 			if err != nil {
-				return mcp.NewToolResultText("BGG ID must be a valid number"), nil
+				return mcp.NewToolResultError("BGG ID must be a valid number"), nil
 			}
 		} else {
-			return mcp.NewToolResultText("Either 'name' or 'id' parameter must be provided"), nil
+			return mcp.NewToolResultError("Either 'name' or 'id' parameter must be provided"), nil
 		}
 
-		minVotes := 30
+		minVotes := CurrentSettings().RecommendationMinVotes
 		if mv, ok := arguments["min_votes"].(float64); ok && mv > 0 {
 			minVotes = int(mv)
 		}
 
 		recommendURL := fmt.Sprintf("https://recommend.games/api/games/%d/similar.json?num_votes__gte=%d&page=1", gameID, minVotes)
 
-		resp, err := http.Get(recommendURL)
+		resp, err := httpGet(ctx, recommendURL)
 		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("Error fetching recommendations: %v", err)), nil
+			return mcp.NewToolResultError(fmt.Sprintf("Error fetching recommendations: %v", err)), nil
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != 200 {
-			return mcp.NewToolResultText(fmt.Sprintf("Recommendation API returned status %d", resp.StatusCode)), nil
+			return mcp.NewToolResultError(fmt.Sprintf("Recommendation API returned status %d", resp.StatusCode)), nil
 		}
 
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("Error reading recommendation response: %v", err)), nil
+			return mcp.NewToolResultError(fmt.Sprintf("Error reading recommendation response: %v", err)), nil
 		}
 
 		var recResponse RecommendGamesResponse
 		if err := json.Unmarshal(body, &recResponse); err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("Error parsing recommendation response: %v", err)), nil
+			return mcp.NewToolResultError(fmt.Sprintf("Error parsing recommendation response: %v", err)), nil
 		}
 
 		recommendedIDs := make([]int, 0, 10)
@@ -98,15 +98,17 @@ func RecommenderTool() (mcp.Tool, server.ToolHandlerFunc) {
 			return mcp.NewToolResultText("No recommendations found"), nil
 		}
 
+		done := traceBGG(ctx, "thing.Query", tracing.Int("bgg.ids", len(recommendedIDs)))
 		gameDetails, err := thing.Query(recommendedIDs)
+		done(err)
 		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("Error fetching game details: %v", err)), nil
+			return mcp.NewToolResultError(fmt.Sprintf("Error fetching game details: %v", err)), nil
 		}
 
 		essentialInfo := extractEssentialInfoList(gameDetails.Items)
 		out, err := json.Marshal(essentialInfo)
 		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("Error formatting results: %v", err)), nil
+			return mcp.NewToolResultError(fmt.Sprintf("Error formatting results: %v", err)), nil
 		}
 
 		return mcp.NewToolResultText(string(out)), nil
@@ -5,11 +5,10 @@ import (
 	"html"
 	"io"
 	"net/http"
-	"net/url"
-	"os"
 	"strconv"
 	"strings"
 
+	"github.com/kkjdanie/bgg-mcp/tracing"
 	"github.com/kkjdaniel/gogeek/collection"
 	"github.com/kkjdaniel/gogeek/forum"
 	"github.com/kkjdaniel/gogeek/forumlist"
@@ -27,23 +26,35 @@ import (
 // GET /v1/bgg/hot
 // GET /v1/bgg/user?username=
 // GET /v1/bgg/collection?username=...&subtype=boardgame|boardgameexpansion&owned=true...
-// GET /v1/bgg/price?ids=12,844&currency=USD&destination=US
+// GET /v1/bgg/price?ids=12,844&name=Azul&username=&collection=wishlist&currency=USD&destination=US&in_stock=true&summary=true
 // GET /v1/bgg/recommendations?name=Azul&id=&min_votes=30
 // GET /v1/bgg/trade-finder?user1=...&user2=...
 // GET /v1/bgg/rules?name=Azul&id=
-// GET /v1/bgg/thread/{id}
+// GET /v1/bgg/thread/{id}?game_id=&official_only=true
+//
+// The search, details, collection, price and trade-finder routes also honour ?format=csv|markdown|bgg_csv
+// or an Accept header of text/csv, text/markdown or text/csv;profile=bgg.
 func RegisterRESTHandlers(mux *http.ServeMux) {
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		writeJSON(w, map[string]string{"status": "ok"})
+	handle := func(pattern string, handler http.HandlerFunc) {
+		mux.Handle(pattern, bggAuthErrors(cachePolicy(handler)))
+	}
+
+	handle("/health", func(w http.ResponseWriter, r *http.Request) {
+		bggAuth := CurrentBGGAuthStatus()
+		status := "ok"
+		if bggAuth.Status == "token_missing" || bggAuth.Status == "token_rejected" {
+			status = "degraded"
+		}
+		writeJSON(w, map[string]any{"status": status, "bgg_api": bggAuth})
 	})
 
-	mux.HandleFunc("/v1/bgg/search", func(w http.ResponseWriter, r *http.Request) {
+	handle("/v1/bgg/search", func(w http.ResponseWriter, r *http.Request) {
 		q := strings.TrimSpace(r.URL.Query().Get("query"))
 		if len(q) < 3 {
 			writeJSON(w, map[string]any{"games": []any{}, "total": 0, "warning": "query too short"})
 			return
 		}
-		limit := 30
+		limit := CurrentSettings().SearchLimit
 		if l := r.URL.Query().Get("limit"); l != "" {
 			if n, err := strconv.Atoi(l); err == nil && n > 0 && n <= 100 {
 				limit = n
@@ -58,33 +69,32 @@ func RegisterRESTHandlers(mux *http.ServeMux) {
 			filterType = "boardgame"
 		}
 
-		items, err := searchAndSortGames(q, filterType, limit)
+		items, err := searchAndSortGames(r.Context(), q, filterType, limit)
 		if err != nil {
 			writeJSON(w, map[string]any{"games": []any{}, "total": 0, "error": err.Error()})
 			return
 		}
 		es := extractEssentialInfoList(items.Items)
-		writeJSON(w, map[string]any{"games": es, "total": len(es)})
+		writeNegotiated(w, r, map[string]any{"games": es, "total": len(es)}, tableFor(func() table { return gameInfoTable(es) }))
 	})
 
-	mux.HandleFunc("/v1/bgg/details/", func(w http.ResponseWriter, r *http.Request) {
+	handle("/v1/bgg/details/", func(w http.ResponseWriter, r *http.Request) {
 		idPart := strings.TrimPrefix(r.URL.Path, "/v1/bgg/details/")
 		if idPart == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			writeJSON(w, map[string]string{"error": "missing id"})
+			writeJSONStatus(w, http.StatusBadRequest, map[string]string{"error": "missing id"})
 			return
 		}
 		id, err := strconv.Atoi(idPart)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			writeJSON(w, map[string]string{"error": "invalid id"})
+			writeJSONStatus(w, http.StatusBadRequest, map[string]string{"error": "invalid id"})
 			return
 		}
 
+		done := traceBGG(r.Context(), "thing.Query", tracing.Int("bgg.ids", 1))
 		things, err := thing.Query([]int{id})
+		done(err)
 		if err != nil || len(things.Items) == 0 {
-			w.WriteHeader(http.StatusNotFound)
-			writeJSON(w, map[string]string{"error": "not found"})
+			writeJSONStatus(w, http.StatusNotFound, map[string]string{"error": "not found"})
 			return
 		}
 		info := extractEssentialInfo(things.Items[0])
@@ -98,11 +108,13 @@ func RegisterRESTHandlers(mux *http.ServeMux) {
 				info.DescriptionShort = clean
 			}
 		}
-		writeJSON(w, info)
+		writeNegotiated(w, r, info, tableFor(func() table { return gameInfoTable([]EssentialGameInfo{info}) }))
 	})
 
-	mux.HandleFunc("/v1/bgg/hot", func(w http.ResponseWriter, r *http.Request) {
+	handle("/v1/bgg/hot", func(w http.ResponseWriter, r *http.Request) {
+		done := traceBGG(r.Context(), "hot.Query")
 		res, err := hot.Query(hot.ItemTypeBoardGame)
+		done(err)
 		if err != nil {
 			writeJSON(w, map[string]any{"error": err.Error()})
 			return
@@ -110,19 +122,18 @@ func RegisterRESTHandlers(mux *http.ServeMux) {
 		writeJSON(w, res.Items)
 	})
 
-	mux.HandleFunc("/v1/bgg/user", func(w http.ResponseWriter, r *http.Request) {
+	handle("/v1/bgg/user", func(w http.ResponseWriter, r *http.Request) {
 		name := strings.TrimSpace(r.URL.Query().Get("username"))
 		if strings.EqualFold(name, "SELF") || name == "" {
-			if env := os.Getenv("BGG_USERNAME"); env != "" {
-				name = env
-			}
+			name, _ = resolveUsername(r.Context(), "SELF")
 		}
 		if name == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			writeJSON(w, map[string]string{"error": "username required"})
+			writeJSONStatus(w, http.StatusBadRequest, map[string]string{"error": "username required"})
 			return
 		}
+		done := traceBGG(r.Context(), "user.Query", tracing.Username("bgg.username", name))
 		ud, err := user.Query(name)
+		done(err)
 		if err != nil {
 			writeJSON(w, map[string]any{"error": err.Error()})
 			return
@@ -130,17 +141,14 @@ func RegisterRESTHandlers(mux *http.ServeMux) {
 		writeJSON(w, ud)
 	})
 
-	mux.HandleFunc("/v1/bgg/collection", func(w http.ResponseWriter, r *http.Request) {
+	handle("/v1/bgg/collection", func(w http.ResponseWriter, r *http.Request) {
 		q := r.URL.Query()
 		name := strings.TrimSpace(q.Get("username"))
 		if strings.EqualFold(name, "SELF") || name == "" {
-			if env := os.Getenv("BGG_USERNAME"); env != "" {
-				name = env
-			}
+			name, _ = resolveUsername(r.Context(), "SELF")
 		}
 		if name == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			writeJSON(w, map[string]string{"error": "username required"})
+			writeJSONStatus(w, http.StatusBadRequest, map[string]string{"error": "username required"})
 			return
 		}
 		// Reuse buildCollectionOptions by translating query params
@@ -156,53 +164,55 @@ func RegisterRESTHandlers(mux *http.ServeMux) {
 		if v := q.Get("maxplays"); v != "" { if f, err := strconv.ParseFloat(v, 64); err == nil { args["maxplays"] = f } }
 
 		opts := buildCollectionOptions(args)
+		done := traceBGG(r.Context(), "collection.Query", tracing.Username("bgg.username", name))
 		res, err := collection.Query(name, opts...)
+		done(err)
 		if err != nil {
 			writeJSON(w, map[string]any{"error": err.Error()})
 			return
 		}
-		writeJSON(w, res.Items)
+		writeNegotiated(w, r, res.Items, collectionTableFor(res.Items))
 	})
 
-	mux.HandleFunc("/v1/bgg/price", func(w http.ResponseWriter, r *http.Request) {
+	handle("/v1/bgg/price", func(w http.ResponseWriter, r *http.Request) {
 		q := r.URL.Query()
-		ids := strings.TrimSpace(q.Get("ids"))
-		if ids == "" { writeJSON(w, map[string]string{"error":"ids required"}); return }
-		currency := strings.ToUpper(strings.TrimSpace(q.Get("currency")))
-		if currency == "" { currency = "USD" }
-		destination := strings.ToUpper(strings.TrimSpace(q.Get("destination")))
-		if destination == "" { destination = "US" }
-		params := url.Values{}
-		params.Add("eid", ids)
-		params.Add("currency", currency)
-		params.Add("destination", destination)
-		params.Add("sitename", "bgg-mcp")
-		resp, err := http.Get("https://boardgameprices.co.uk/api/info?" + params.Encode())
+		targets := PriceTargets{
+			IDs:        strings.TrimSpace(q.Get("ids")),
+			Names:      q["name"],
+			Username:   strings.TrimSpace(q.Get("username")),
+			Collection: strings.TrimSpace(q.Get("collection")),
+		}
+		query := PriceQuery{
+			Currency:    strings.ToUpper(strings.TrimSpace(q.Get("currency"))),
+			Destination: strings.ToUpper(strings.TrimSpace(q.Get("destination"))),
+			InStockOnly: q.Get("in_stock") == "true" || q.Get("in_stock") == "1",
+			Summary:     targets.Username != "",
+		}
+		if v := q.Get("summary"); v != "" { query.Summary = v == "true" || v == "1" }
+		if query.Currency == "" { query.Currency = CurrentSettings().Currency }
+		if query.Destination == "" { query.Destination = CurrentSettings().Destination }
+		result, err := lookupPrices(r.Context(), targets, query)
 		if err != nil { writeJSON(w, map[string]string{"error": err.Error()}); return }
-		defer resp.Body.Close()
-		body, _ := io.ReadAll(resp.Body)
-		var out any
-		if err := json.Unmarshal(body, &out); err != nil { writeJSON(w, map[string]string{"error": err.Error()}); return }
-		writeJSON(w, out)
+		writeNegotiated(w, r, result, tableFor(func() table { return priceTable(result) }))
 	})
 
-	mux.HandleFunc("/v1/bgg/recommendations", func(w http.ResponseWriter, r *http.Request) {
+	handle("/v1/bgg/recommendations", func(w http.ResponseWriter, r *http.Request) {
 		q := r.URL.Query()
 		name := strings.TrimSpace(q.Get("name"))
 		idStr := strings.TrimSpace(q.Get("id"))
-		minVotes := 30
+		minVotes := CurrentSettings().RecommendationMinVotes
 		if v := q.Get("min_votes"); v != "" { if n, err := strconv.Atoi(v); err == nil && n > 0 { minVotes = n } }
 		var gameID int
 		if idStr != "" {
 			if n, err := strconv.Atoi(idStr); err == nil { gameID = n }
 		}
 		if gameID == 0 && name != "" {
-			items, err := searchAndSortGames(name, "boardgame", 1)
+			items, err := searchAndSortGames(r.Context(), name, "boardgame", 1)
 			if err == nil && len(items.Items) > 0 { gameID = items.Items[0].ID }
 		}
 		if gameID == 0 { writeJSON(w, map[string]string{"error":"name or id required"}); return }
 		recURL := "https://recommend.games/api/games/" + strconv.Itoa(gameID) + "/similar.json?num_votes__gte=" + strconv.Itoa(minVotes) + "&page=1"
-		resp, err := http.Get(recURL)
+		resp, err := httpGet(r.Context(), recURL)
 		if err != nil { writeJSON(w, map[string]string{"error": err.Error()}); return }
 		defer resp.Body.Close()
 		b, _ := io.ReadAll(resp.Body)
@@ -211,26 +221,33 @@ func RegisterRESTHandlers(mux *http.ServeMux) {
 		if len(parsed.Results) == 0 { writeJSON(w, []any{}); return }
 		ids := make([]int, 0, len(parsed.Results))
 		for i, g := range parsed.Results { if i >= 10 { break }; ids = append(ids, g.BGGID) }
+		done := traceBGG(r.Context(), "thing.Query", tracing.Int("bgg.ids", len(ids)))
 		things, err := thing.Query(ids)
+		done(err)
 		if err != nil { writeJSON(w, map[string]string{"error": err.Error()}); return }
 		writeJSON(w, extractEssentialInfoList(things.Items))
 	})
 
-	mux.HandleFunc("/v1/bgg/trade-finder", func(w http.ResponseWriter, r *http.Request) {
+	handle("/v1/bgg/trade-finder", func(w http.ResponseWriter, r *http.Request) {
 		q := r.URL.Query()
 		u1 := strings.TrimSpace(q.Get("user1"))
 		u2 := strings.TrimSpace(q.Get("user2"))
-		if u1 == "SELF" || u1 == "" { if env := os.Getenv("BGG_USERNAME"); env != "" { u1 = env } }
-		if u2 == "SELF" { if env := os.Getenv("BGG_USERNAME"); env != "" { u2 = env } }
+		if u1 == "SELF" || u1 == "" { u1, _ = resolveUsername(r.Context(), "SELF") }
+		if u2 == "SELF" { u2, _ = resolveUsername(r.Context(), "SELF") }
 		if u1 == "" || u2 == "" { writeJSON(w, map[string]string{"error":"user1 and user2 required"}); return }
+		done := traceBGG(r.Context(), "collection.Query", tracing.Username("bgg.username", u1), tracing.Bool("bgg.owned", true))
 		u1Col, err := collection.Query(u1, collection.WithOwned(true))
+		done(err)
 		if err != nil { writeJSON(w, map[string]string{"error": err.Error()}); return }
+		done = traceBGG(r.Context(), "collection.Query", tracing.Username("bgg.username", u2), tracing.Bool("bgg.wishlist", true))
 		u2Wish, err := collection.Query(u2, collection.WithWishlist(true))
+		done(err)
 		if err != nil { writeJSON(w, map[string]string{"error": err.Error()}); return }
-		writeJSON(w, analyseTradeOpportunities(u1, u2, u1Col, u2Wish))
+		trade := analyseTradeOpportunities(u1, u2, u1Col, u2Wish)
+		writeNegotiated(w, r, trade, tableFor(func() table { return tradeTable(trade) }))
 	})
 
-	mux.HandleFunc("/v1/bgg/rules", func(w http.ResponseWriter, r *http.Request) {
+	handle("/v1/bgg/rules", func(w http.ResponseWriter, r *http.Request) {
 		q := r.URL.Query()
 		name := strings.TrimSpace(q.Get("name"))
 		idStr := strings.TrimSpace(q.Get("id"))
@@ -240,11 +257,13 @@ func RegisterRESTHandlers(mux *http.ServeMux) {
 			if n, err := strconv.Atoi(idStr); err == nil { gameID = n }
 		}
 		if gameID == 0 && name != "" {
-			best, err := findBestGameMatch(name)
+			best, err := findBestGameMatch(r.Context(), name)
 			if err == nil && best != nil { gameID = best.ID; gameName = best.Name.Value }
 		}
 		if gameID == 0 { writeJSON(w, map[string]string{"error":"name or id required"}); return }
+		done := traceBGG(r.Context(), "forumlist.Query", tracing.Int("bgg.id", gameID))
 		forums, err := forumlist.Query(gameID, forumlist.Thing)
+		done(err)
 		if err != nil { writeJSON(w, map[string]string{"error": err.Error()}); return }
 		var rulesForumID int
 		var rulesForumTitle string
@@ -256,7 +275,9 @@ func RegisterRESTHandlers(mux *http.ServeMux) {
 		threads := []map[string]any{}
 		page := 1
 		for page <= 3 { // cap pages for REST
+			done := traceBGG(r.Context(), "forum.Query", tracing.Int("bgg.forum_id", rulesForumID), tracing.Int("bgg.page", page))
 			fd, err := forum.Query(rulesForumID, forum.WithPage(page))
+			done(err)
 			if err != nil { break }
 			for _, th := range fd.Threads {
 				threads = append(threads, map[string]any{"id": th.ID, "subject": th.Subject, "replies": th.NumArticles - 1, "link": "https://boardgamegeek.com/thread/" + strconv.Itoa(th.ID) })
@@ -272,24 +293,62 @@ func RegisterRESTHandlers(mux *http.ServeMux) {
 		})
 	})
 
-	mux.HandleFunc("/v1/bgg/thread/", func(w http.ResponseWriter, r *http.Request) {
+	handle("/v1/bgg/thread/", func(w http.ResponseWriter, r *http.Request) {
 		idPart := strings.TrimPrefix(r.URL.Path, "/v1/bgg/thread/")
 		id, err := strconv.Atoi(idPart)
 		if err != nil { writeJSON(w, map[string]string{"error":"invalid id"}); return }
+		done := traceBGG(r.Context(), "thread.Query", tracing.Int("bgg.thread_id", id))
 		td, err := thread.Query(id)
+		done(err)
 		if err != nil { writeJSON(w, map[string]string{"error": err.Error()}); return }
-		writeJSON(w, td)
+		var game *thing.Item
+		if gid, err := strconv.Atoi(r.URL.Query().Get("game_id")); err == nil && gid > 0 {
+			done := traceBGG(r.Context(), "thing.Query", tracing.Int("bgg.ids", 1))
+			things, err := thing.Query([]int{gid})
+			done(err)
+			if err == nil && len(things.Items) > 0 { game = &things.Items[0] }
+		}
+		officialOnly := r.URL.Query().Get("official_only") == "true"
+		writeJSON(w, flagOfficialPosts(td, newOfficialAuthors(game), officialOnly))
 	})
 }
 
 func writeJSON(w http.ResponseWriter, v any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-	w.Header().Set("Cache-Control", "public, max-age=3600")
+	writeJSONStatus(w, http.StatusOK, v)
+}
+
+// writeJSONStatus writes v as JSON with the given status. Headers are set before the status
+// line is written, so error responses carry them too.
+func writeJSONStatus(w http.ResponseWriter, status int, v any) {
+	setAPIHeaders(w, "application/json")
+	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(v)
 }
 
+// setAPIHeaders sets the content type, CORS and caching headers shared by every REST response.
+// Responses are public unless cachePolicy already marked them private.
+func setAPIHeaders(w http.ResponseWriter, contentType string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Accept, Authorization, X-API-Key, X-BGG-Username")
+	if w.Header().Get("Cache-Control") == "" {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+	}
+}
+
+// cachePolicy keeps shared caches from serving one caller's response to another. Responses
+// to authenticated requests, or to requests naming a BGG user that SELF resolves to, may
+// only be cached by the client.
+func cachePolicy(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Vary", "Authorization, X-API-Key, X-BGG-Username")
+		if r.Header.Get("Authorization") != "" || r.Header.Get("X-API-Key") != "" || requestUsername(r.Context()) != "" {
+			w.Header().Set("Cache-Control", "private, max-age=3600")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func sanitizeDescription(s string) string {
 	// Decode HTML entities
 	decoded := html.UnescapeString(s)
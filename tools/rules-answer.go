@@ -0,0 +1,284 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/kkjdanie/bgg-mcp/tracing"
+	"github.com/kkjdaniel/gogeek/thing"
+	"github.com/kkjdaniel/gogeek/thread"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// officialPostBoost multiplies the score of passages written by the game's designer or publisher.
+const officialPostBoost = 1.5
+
+type RulesPassage struct {
+	Text          string  `json:"text"`
+	Score         float64 `json:"score"`
+	Author        string  `json:"author"`
+	PostDate      string  `json:"post_date"`
+	Official      bool    `json:"official,omitempty"`
+	OfficialRole  string  `json:"official_role,omitempty"`
+	ThreadID      int     `json:"thread_id"`
+	ThreadSubject string  `json:"thread_subject"`
+	ArticleID     int     `json:"article_id"`
+	Link          string  `json:"link"`
+}
+
+func RulesAnswerTool() (mcp.Tool, server.ToolHandlerFunc) {
+	tool := mcp.NewTool("bgg-rules-answer",
+		mcp.WithDescription("Use this tool to answer a specific rules question about a board game. Finds the most relevant BoardGameGeek (BGG) rules threads, reads them, and returns the passages that best answer the question with author, date and a link to each post as citations. Posts by the game's designer or publisher are ranked higher."),
+		mcp.WithString("name",
+			mcp.Description("The name of the board game"),
+		),
+		mcp.WithNumber("id",
+			mcp.Description("The BoardGameGeek ID of the board game"),
+		),
+		mcp.WithString("question",
+			mcp.Required(),
+			mcp.Description("The rules question to answer (e.g., 'Can I play a worker on an occupied action space?')"),
+		),
+		mcp.WithNumber("threads",
+			mcp.Description("Number of the most relevant threads to read (default: 5)"),
+		),
+		mcp.WithNumber("passages",
+			mcp.Description("Number of passages to return (default: 6)"),
+		),
+		mcp.WithBoolean("official_only",
+			mcp.Description("Only return passages from official rulings by the designer or publisher (default: false)"),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		arguments := request.GetArguments()
+
+		question, ok := arguments["question"].(string)
+		if !ok || strings.TrimSpace(question) == "" {
+			return mcp.NewToolResultError("question parameter is required"), nil
+		}
+		queryTerms := tokenize(question)
+		if len(queryTerms) == 0 {
+			return mcp.NewToolResultError("question must contain at least one keyword"), nil
+		}
+
+		gameID, gameName, err := resolveGame(ctx, arguments)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		maxThreads := 5
+		if t, ok := arguments["threads"].(float64); ok && t > 0 {
+			maxThreads = int(t)
+		}
+		maxPassages := 6
+		if p, ok := arguments["passages"].(float64); ok && p > 0 {
+			maxPassages = int(p)
+		}
+
+		done := traceBGG(ctx, "thing.Query", tracing.Int("bgg.ids", 1))
+		things, err := thing.Query([]int{gameID})
+		done(err)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get game details: %v", err)), nil
+		}
+		if len(things.Items) == 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("No game found with ID %d", gameID)), nil
+		}
+		game := things.Items[0]
+		if gameName == "" {
+			gameName = game.Name[0].Value
+		}
+
+		candidates, _, err := collectForumThreads(ctx, gameID, []string{"rules"}, CurrentSettings().ForumSearchMaxPages)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if len(candidates) == 0 {
+			return mcp.NewToolResultText(fmt.Sprintf("No rules forum threads found for game ID %d", gameID)), nil
+		}
+
+		threadIDs := []int{}
+		for _, r := range rankForumThreads(candidates, queryTerms) {
+			if len(threadIDs) >= maxThreads {
+				break
+			}
+			threadIDs = append(threadIDs, candidates[r.Index].Thread.ID)
+		}
+		if len(threadIDs) == 0 {
+			return mcp.NewToolResultText("No rules threads matched the question. Try rephrasing it with the game's terminology, or use bgg-rules to browse thread titles."), nil
+		}
+
+		officialOnly, _ := arguments["official_only"].(bool)
+
+		official := newOfficialAuthors(&game)
+		passages := []RulesPassage{}
+		for _, id := range threadIDs {
+			done := traceBGG(ctx, "thread.Query", tracing.Int("bgg.thread_id", id))
+			td, err := thread.Query(id)
+			done(err)
+			if err != nil {
+				continue
+			}
+			for _, p := range threadPassages(td, official) {
+				if officialOnly && !p.Official {
+					continue
+				}
+				passages = append(passages, p)
+			}
+		}
+
+		top := rankPassages(passages, queryTerms, maxPassages)
+
+		return mcp.NewToolResultText(formatRulesAnswer(gameName, gameID, question, len(threadIDs), official.Note(), top)), nil
+	}
+
+	return tool, handler
+}
+
+// threadPassages splits every article in a thread into passages of roughly a paragraph each.
+func threadPassages(td *thread.ThreadDetail, official *officialAuthors) []RulesPassage {
+	var passages []RulesPassage
+	for _, article := range td.Articles {
+		body := sanitizeDescription(stripHTML(article.Body))
+		role := official.Role(article.Username)
+		for _, text := range splitPassages(body) {
+			passages = append(passages, RulesPassage{
+				Text:          text,
+				Author:        article.Username,
+				PostDate:      article.PostDate,
+				Official:      role != "",
+				OfficialRole:  role,
+				ThreadID:      td.ID,
+				ThreadSubject: td.Subject,
+				ArticleID:     article.ID,
+				Link:          fmt.Sprintf("https://boardgamegeek.com/thread/%d/article/%d#%d", td.ID, article.ID, article.ID),
+			})
+		}
+	}
+	return passages
+}
+
+// splitPassages groups paragraphs into passages of at least 60 words and splits
+// paragraphs longer than 150 words into chunks of 120.
+func splitPassages(body string) []string {
+	const minWords, maxWords, chunkWords = 60, 150, 120
+
+	var passages []string
+	var current []string
+	flush := func() {
+		if len(current) > 0 {
+			passages = append(passages, strings.Join(current, " "))
+			current = nil
+		}
+	}
+
+	for _, paragraph := range strings.Split(body, "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			continue
+		}
+		if len(words) > maxWords {
+			flush()
+			for i := 0; i < len(words); i += chunkWords {
+				end := i + chunkWords
+				if end > len(words) {
+					end = len(words)
+				}
+				passages = append(passages, strings.Join(words[i:end], " "))
+			}
+			continue
+		}
+		current = append(current, words...)
+		if len(current) >= minWords {
+			flush()
+		}
+	}
+	flush()
+
+	return passages
+}
+
+// rankPassages scores passages with BM25, boosts official posts and keeps the best passage
+// per article so one long post cannot crowd out the rest.
+func rankPassages(passages []RulesPassage, queryTerms []string, limit int) []RulesPassage {
+	docs := make([][]string, len(passages))
+	for i, p := range passages {
+		docs[i] = tokenize(p.Text)
+	}
+	idx := newBM25Index(docs)
+
+	scored := []RulesPassage{}
+	for i := range passages {
+		score := idx.Score(i, queryTerms)
+		if score <= 0 {
+			continue
+		}
+		if passages[i].Official {
+			score *= officialPostBoost
+		}
+		p := passages[i]
+		p.Score = roundScore(score)
+		scored = append(scored, p)
+	}
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].Score > scored[j].Score })
+
+	top := []RulesPassage{}
+	seenArticles := map[int]bool{}
+	for _, p := range scored {
+		if len(top) >= limit {
+			break
+		}
+		if seenArticles[p.ArticleID] {
+			continue
+		}
+		seenArticles[p.ArticleID] = true
+		top = append(top, p)
+	}
+	return top
+}
+
+func formatRulesAnswer(gameName string, gameID int, question string, threadsRead int, officialNote string, passages []RulesPassage) string {
+	var response strings.Builder
+	response.WriteString("<rules_answer>\n")
+	response.WriteString("<instructions>\n")
+	response.WriteString("Answer the user's rules question using ONLY the passages below.\n")
+	response.WriteString("IMPORTANT: First verify the game found matches what the user is asking about. If it seems wrong, mention it.\n")
+	response.WriteString("1. Prefer passages marked official=\"true\" (posted by the designer or publisher) over community answers\n")
+	response.WriteString("2. Cite each claim with the author, date and link of the passage it came from\n")
+	response.WriteString("3. If passages disagree, say so and explain which source is more authoritative\n")
+	response.WriteString("4. If no passage answers the question, say so and suggest bgg-thread-details on the closest thread\n")
+	response.WriteString("</instructions>\n\n")
+
+	response.WriteString("<game_context>\n")
+	response.WriteString(fmt.Sprintf("  <game_name>%s</game_name>\n", html.EscapeString(gameName)))
+	response.WriteString(fmt.Sprintf("  <game_id>%d</game_id>\n", gameID))
+	response.WriteString(fmt.Sprintf("  <question>%s</question>\n", html.EscapeString(question)))
+	response.WriteString(fmt.Sprintf("  <threads_read>%d</threads_read>\n", threadsRead))
+	if officialNote != "" {
+		response.WriteString(fmt.Sprintf("  <official_note>%s</official_note>\n", html.EscapeString(officialNote)))
+	}
+	response.WriteString("</game_context>\n\n")
+
+	response.WriteString("<passages>\n")
+	for i, p := range passages {
+		response.WriteString(fmt.Sprintf("  <passage rank=\"%d\" score=\"%.2f\" official=\"%t\">\n", i+1, p.Score, p.Official))
+		response.WriteString(fmt.Sprintf("    <text>%s</text>\n", html.EscapeString(p.Text)))
+		if p.Official {
+			response.WriteString(fmt.Sprintf("    <official_ruling>%s</official_ruling>\n", html.EscapeString(p.OfficialRole)))
+		}
+		response.WriteString(fmt.Sprintf("    <author>%s</author>\n", html.EscapeString(p.Author)))
+		response.WriteString(fmt.Sprintf("    <date>%s</date>\n", html.EscapeString(p.PostDate)))
+		response.WriteString(fmt.Sprintf("    <thread_subject>%s</thread_subject>\n", html.EscapeString(p.ThreadSubject)))
+		response.WriteString(fmt.Sprintf("    <link>%s</link>\n", p.Link))
+		response.WriteString("  </passage>\n")
+	}
+	response.WriteString("</passages>\n")
+	response.WriteString("</rules_answer>\n")
+
+	return response.String()
+}
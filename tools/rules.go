@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/kkjdanie/bgg-mcp/tracing"
 	"github.com/kkjdaniel/gogeek/forum"
 	"github.com/kkjdaniel/gogeek/forumlist"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -47,24 +48,26 @@ func RulesTool() (mcp.Tool, server.ToolHandlerFunc) {
 			case string:
 				gameID, err = strconv.Atoi(v)
 				if err != nil {
-					return mcp.NewToolResultText("Invalid game ID format"), nil
+					return mcp.NewToolResultError("Invalid game ID format"), nil
 				}
 			}
 		} else if nameVal, ok := arguments["name"]; ok && nameVal != nil {
 			gameName = nameVal.(string)
-			bestMatch, err := findBestGameMatch(gameName)
+			bestMatch, err := findBestGameMatch(ctx, gameName)
 			if err != nil {
-				return mcp.NewToolResultText(fmt.Sprintf("Failed to find game: %v", err)), nil
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to find game: %v", err)), nil
 			}
 			gameID = bestMatch.ID
 			gameName = bestMatch.Name.Value
 		} else {
-			return mcp.NewToolResultText("Either 'name' or 'id' parameter is required"), nil
+			return mcp.NewToolResultError("Either 'name' or 'id' parameter is required"), nil
 		}
 
+		done := traceBGG(ctx, "forumlist.Query", tracing.Int("bgg.id", gameID))
 		forums, err := forumlist.Query(gameID, forumlist.Thing)
+		done(err)
 		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("Failed to get forum list: %v", err)), nil
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get forum list: %v", err)), nil
 		}
 		var rulesForumID int
 		var rulesForumTitle string
@@ -90,20 +93,22 @@ func RulesTool() (mcp.Tool, server.ToolHandlerFunc) {
 
 		allThreads := []forum.Thread{}
 		page := 1
-		maxPages := 10 // Reasonable max to avoid infinite loops
+		maxPages := CurrentSettings().RulesMaxPages // Reasonable max to avoid infinite loops
 
 		for page <= maxPages {
 			var rulesForumData *forum.Forum
 			var err error
 
+			done := traceBGG(ctx, "forum.Query", tracing.Int("bgg.forum_id", rulesForumID), tracing.Int("bgg.page", page))
 			if page == 1 {
 				rulesForumData, err = forum.Query(rulesForumID)
 			} else {
 				rulesForumData, err = forum.Query(rulesForumID, forum.WithPage(page))
 			}
+			done(err)
 
 			if err != nil {
-				return mcp.NewToolResultText(fmt.Sprintf("Failed to get rules forum threads: %v", err)), nil
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to get rules forum threads: %v", err)), nil
 			}
 
 			if page == 1 {
@@ -135,9 +140,10 @@ func RulesTool() (mcp.Tool, server.ToolHandlerFunc) {
 		response.WriteString("1. Identify threads that directly address the user's specific rules query based on their titles\n")
 		response.WriteString("2. Look for threads with high reply counts (indicating thorough discussions) or official-sounding titles\n")
 		response.WriteString("3. Present the 1-4 most relevant threads with brief descriptions of what the titles suggest they discuss\n")
-		response.WriteString("4. For the most promising thread(s), proactively use bgg-thread-details to fetch the actual content\n")
+		response.WriteString("4. For the most promising thread(s), proactively use bgg-thread-details (with game_id set) to fetch the actual content\n")
 		response.WriteString("5. After reading the thread content, provide a clear answer to the user's rules question\n")
 		response.WriteString("Remember: You're seeing thread titles only. Use bgg-thread-details to get actual answers.\n")
+		response.WriteString("Posts flagged official in bgg-thread-details come from the designer or publisher; treat them as authoritative rulings.\n")
 		response.WriteString("</instructions>\n\n")
 
 		response.WriteString("<game_context>\n")
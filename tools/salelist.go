@@ -0,0 +1,275 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kkjdanie/bgg-mcp/tracing"
+	"github.com/kkjdaniel/gogeek/collection"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+var (
+	saleListFormats = []string{FormatMarkdown, FormatBBCode, FormatPlain, FormatJSON}
+	saleRoundings   = []string{"nearest", "down", "up"}
+)
+
+const (
+	defaultSaleTitle  = "🎲 BOARD GAMES FOR SALE 🎲"
+	defaultSaleFooter = "DM for more info or bundle deals!"
+)
+
+// SaleList is a user's for-trade games priced at a discount from current retail prices.
+type SaleList struct {
+	Username string     `json:"username"`
+	Currency string     `json:"currency"`
+	Discount float64    `json:"discount"`
+	Items    []SaleItem `json:"items"`
+	// RatesDate is the date of the exchange rates used when any retail price was converted.
+	RatesDate string   `json:"rates_date,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+	Post      string   `json:"post"`
+}
+
+// SaleItem is one copy for sale. Price is nil when no retailer price was found, which the
+// post shows as TBD.
+type SaleItem struct {
+	GameID      int      `json:"game_id"`
+	Name        string   `json:"name"`
+	RetailPrice float64  `json:"retail_price,omitempty"`
+	Price       *float64 `json:"price"`
+	Store       string   `json:"store,omitempty"`
+}
+
+// SaleRules set how a sale price is derived from the retail price: Discount percent off,
+// then rounded to a multiple of RoundTo.
+type SaleRules struct {
+	Discount float64
+	RoundTo  float64
+	Rounding string
+}
+
+// Apply returns the sale price for a retail price. It never rounds a price down to zero.
+func (r SaleRules) Apply(retail float64) float64 {
+	price := retail * (1 - r.Discount/100)
+	if r.RoundTo <= 0 {
+		return round2(price)
+	}
+	steps := price / r.RoundTo
+	switch r.Rounding {
+	case "down":
+		steps = math.Floor(steps)
+	case "up":
+		steps = math.Ceil(steps)
+	default:
+		steps = math.Round(steps)
+	}
+	return round2(math.Max(1, steps) * r.RoundTo)
+}
+
+func SaleListTool() (mcp.Tool, server.ToolHandlerFunc) {
+	tool := mcp.NewTool("bgg-sale-list",
+		mcp.WithDescription("Write a ready-to-post sales list of a BoardGameGeek (BGG) user's games marked for trade. Every game is priced in one batch from current retail prices, discounted and rounded by fixed rules; games without a price are listed as TBD. Renders the post as Markdown, BBCode (for BGG forums) or plain text."),
+		mcp.WithString("username",
+			mcp.Required(),
+			mcp.Description("The BGG username. When the user refers to themselves (me, my, I), use 'SELF' as the value."),
+		),
+		mcp.WithString("currency",
+			mcp.Description(fmt.Sprintf("ISO 4217 currency code (default: %s)", CurrentSettings().Currency)),
+		),
+		mcp.WithString("destination",
+			mcp.Description(fmt.Sprintf("Two-letter country used to look up retail prices (default: %s)", CurrentSettings().Destination)),
+		),
+		mcp.WithNumber("discount",
+			mcp.Description("Percent off the current retail price, from 0 to 90 (default: 20)"),
+		),
+		mcp.WithNumber("round_to",
+			mcp.Description("Round sale prices to a multiple of this amount, e.g. 1, 5 or 0.5; 0 keeps cents (default: 1)"),
+		),
+		mcp.WithString("rounding",
+			mcp.Enum(saleRoundings...),
+			mcp.Description("Direction to round sale prices (default: nearest)"),
+		),
+		mcp.WithString("title",
+			mcp.Description(fmt.Sprintf("Heading of the post (default: %s)", defaultSaleTitle)),
+		),
+		mcp.WithString("footer",
+			mcp.Description(fmt.Sprintf("Closing line of the post (default: %s)", defaultSaleFooter)),
+		),
+		mcp.WithString("format",
+			mcp.Enum(saleListFormats...),
+			mcp.Description("Output format (default: markdown). Use bbcode for BGG forums, plain for marketplaces without formatting, or json for the priced items and the Markdown post."),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		arguments := request.GetArguments()
+
+		username, _ := arguments["username"].(string)
+		if username == "" {
+			return mcp.NewToolResultError("username is required"), nil
+		}
+		username, err := resolveUsername(ctx, username)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		format := FormatMarkdown
+		if f, ok := arguments["format"].(string); ok && f != "" {
+			format = strings.ToLower(f)
+		}
+		if !containsFold(saleListFormats, format) {
+			return mcp.NewToolResultError(fmt.Sprintf("format must be one of: %s", strings.Join(saleListFormats, ", "))), nil
+		}
+
+		rules := SaleRules{Discount: 20, RoundTo: 1, Rounding: "nearest"}
+		if d, ok := arguments["discount"].(float64); ok {
+			if d < 0 || d > 90 {
+				return mcp.NewToolResultError("discount must be between 0 and 90"), nil
+			}
+			rules.Discount = d
+		}
+		if r, ok := arguments["round_to"].(float64); ok {
+			if r < 0 {
+				return mcp.NewToolResultError("round_to must not be negative"), nil
+			}
+			rules.RoundTo = r
+		}
+		if r, ok := arguments["rounding"].(string); ok && r != "" {
+			if !containsFold(saleRoundings, r) {
+				return mcp.NewToolResultError(fmt.Sprintf("rounding must be one of: %s", strings.Join(saleRoundings, ", "))), nil
+			}
+			rules.Rounding = strings.ToLower(r)
+		}
+
+		query := PriceQuery{
+			Currency:    CurrentSettings().Currency,
+			Destination: CurrentSettings().Destination,
+			Summary:     true,
+			Names:       map[int]string{},
+		}
+		if c, ok := arguments["currency"].(string); ok && c != "" {
+			query.Currency = strings.ToUpper(c)
+		}
+		if d, ok := arguments["destination"].(string); ok && d != "" {
+			query.Destination = strings.ToUpper(d)
+		}
+
+		done := traceBGG(ctx, "collection.Query", tracing.Username("bgg.username", username), tracing.Bool("bgg.fortrade", true))
+		forTrade, err := collection.Query(username, collection.WithTrade(true))
+		done(err)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error fetching for-trade games: %v", err)), nil
+		}
+		if len(forTrade.Items) == 0 {
+			return mcp.NewToolResultText(fmt.Sprintf("%s has no games marked for trade", username)), nil
+		}
+
+		list := &SaleList{Username: username, Currency: query.Currency, Discount: rules.Discount}
+		for _, item := range forTrade.Items {
+			if _, ok := query.Names[item.ObjectID]; !ok {
+				query.IDs = append(query.IDs, item.ObjectID)
+				query.Names[item.ObjectID] = item.Name
+			}
+			list.Items = append(list.Items, SaleItem{GameID: item.ObjectID, Name: item.Name})
+		}
+		if len(query.IDs) > maxPriceGames {
+			return mcp.NewToolResultError(fmt.Sprintf("%s has %d games marked for trade; at most %d can be priced at once", username, len(query.IDs), maxPriceGames)), nil
+		}
+
+		prices, err := fetchPrices(ctx, query)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		list.RatesDate = prices.RatesDate
+		list.Warnings = prices.Warnings
+		priceSaleItems(list.Items, prices, rules)
+
+		if format == FormatJSON {
+			list.Post = renderSaleList(list, FormatMarkdown, stringArg(arguments["title"]), stringArg(arguments["footer"]))
+			return formatToolResult(FormatJSON, list, nil)
+		}
+		return mcp.NewToolResultText(renderSaleList(list, format, stringArg(arguments["title"]), stringArg(arguments["footer"]))), nil
+	}
+
+	return tool, handler
+}
+
+// priceSaleItems sets each item's sale price from its game's best retail offer, and sorts the
+// items by name.
+func priceSaleItems(items []SaleItem, prices *PriceResult, rules SaleRules) {
+	offers := map[int]*PriceOffer{}
+	for _, g := range prices.Games {
+		offers[g.GameID] = g.BestOffer
+	}
+	for i := range items {
+		if o := offers[items[i].GameID]; o != nil && o.Price > 0 {
+			price := rules.Apply(o.Price)
+			items[i].RetailPrice = o.Price
+			items[i].Price = &price
+			items[i].Store = o.Store
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return strings.ToLower(items[i].Name) < strings.ToLower(items[j].Name)
+	})
+}
+
+// renderSaleList writes the sales post in the given format. Every game starts as available;
+// the legend lets the seller mark games pending or sold by editing the emoji.
+func renderSaleList(list *SaleList, format, title, footer string) string {
+	if title == "" {
+		title = defaultSaleTitle
+	}
+	if footer == "" {
+		footer = defaultSaleFooter
+	}
+
+	var sb strings.Builder
+	switch format {
+	case FormatBBCode:
+		sb.WriteString("[size=14][b]" + title + "[/b][/size]\n\n")
+	case FormatPlain:
+		sb.WriteString(title + "\n\n")
+	default:
+		sb.WriteString("## " + title + "\n\n")
+	}
+
+	for _, item := range list.Items {
+		price := "Price TBD"
+		if item.Price != nil {
+			price = formatSalePrice(*item.Price, list.Currency)
+		}
+		switch format {
+		case FormatBBCode:
+			fmt.Fprintf(&sb, "🟢 [thing=%d]%s[/thing] - [b]%s[/b]\n", item.GameID, item.Name, price)
+		case FormatPlain:
+			fmt.Fprintf(&sb, "🟢 %s - %s\n", item.Name, price)
+		default:
+			fmt.Fprintf(&sb, "- 🟢 [%s](https://boardgamegeek.com/boardgame/%d) - **%s**\n", item.Name, item.GameID, price)
+		}
+	}
+
+	sb.WriteString("\n🟢 = Available, 🟡 = Pending, 🔴 = Sold\n")
+	if list.Discount > 0 {
+		fmt.Fprintf(&sb, "Prices are %s%% below current retail.\n", strconv.FormatFloat(list.Discount, 'f', -1, 64))
+	}
+	if list.RatesDate != "" {
+		fmt.Fprintf(&sb, "Some retail prices were converted to %s with exchange rates from %s.\n", list.Currency, list.RatesDate)
+	}
+	sb.WriteString("\n" + footer + "\n")
+	return sb.String()
+}
+
+// formatSalePrice writes whole amounts without cents, e.g. "36 USD" or "12.50 GBP".
+func formatSalePrice(price float64, currency string) string {
+	if price == math.Trunc(price) {
+		return fmt.Sprintf("%.0f %s", price, currency)
+	}
+	return fmt.Sprintf("%.2f %s", price, currency)
+}
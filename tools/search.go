@@ -2,11 +2,11 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
 
+	"github.com/kkjdanie/bgg-mcp/tracing"
 	"github.com/kkjdaniel/gogeek/search"
 	"github.com/kkjdaniel/gogeek/thing"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -21,19 +21,20 @@ func SearchTool() (mcp.Tool, server.ToolHandlerFunc) {
 			mcp.Description("Game name to search for on BoardGameGeek (BGG)"),
 		),
 		mcp.WithNumber("limit",
-			mcp.Description("Maximum number of results to return (default: 30)"),
+			mcp.Description(fmt.Sprintf("Maximum number of results to return (default: %d)", CurrentSettings().SearchLimit)),
 		),
 		mcp.WithString("type",
 			mcp.Description("Filter by type (default: all, options: 'boardgame' (aka base game), 'boardgameexpansion', or 'all')"),
 			mcp.Enum("all", "boardgame", "boardgameexpansion"),
 		),
+		withFormatArgument(FormatJSON, FormatCSV, FormatMarkdown),
 	)
 
 	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		arguments := request.GetArguments()
 		query := arguments["query"].(string)
 
-		limit := 30
+		limit := CurrentSettings().SearchLimit
 		if l, ok := arguments["limit"].(float64); ok {
 			limit = int(l)
 		}
@@ -43,25 +44,24 @@ func SearchTool() (mcp.Tool, server.ToolHandlerFunc) {
 			typeFilter = t
 		}
 
-		gameDetails, err := searchAndSortGames(query, typeFilter, limit)
+		gameDetails, err := searchAndSortGames(ctx, query, typeFilter, limit)
 		if err != nil {
-			return mcp.NewToolResultText(err.Error()), nil
+			return mcp.NewToolResultError(err.Error()), nil
 		}
 
 		essentialInfo := extractEssentialInfoList(gameDetails.Items)
-		out, err := json.Marshal(essentialInfo)
-		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("JSON encoding error: %v", err)), nil
-		}
-
-		return mcp.NewToolResultText(string(out)), nil
+		return formatToolResult(formatArgument(arguments), essentialInfo, tableFor(func() table {
+			return gameInfoTable(essentialInfo)
+		}))
 	}
 
 	return tool, handler
 }
 
-func searchAndSortGames(query, typeFilter string, limit int) (*thing.Items, error) {
+func searchAndSortGames(ctx context.Context, query, typeFilter string, limit int) (*thing.Items, error) {
+	done := traceBGG(ctx, "search.Query", tracing.String("bgg.query", query), tracing.Bool("bgg.exact", false))
 	result, err := search.Query(query, false)
+	done(err)
 	if err != nil {
 		return nil, fmt.Errorf("search error: %v", err)
 	}
@@ -127,22 +127,9 @@ func searchAndSortGames(query, typeFilter string, limit int) (*thing.Items, erro
 		gameIDs = append(gameIDs, item.ID)
 	}
 
-	var allItems []thing.Item
-	maxBatch := 20
-
-	for i := 0; i < len(gameIDs); i += maxBatch {
-		end := i + maxBatch
-		if end > len(gameIDs) {
-			end = len(gameIDs)
-		}
-
-		batch := gameIDs[i:end]
-		gameDetails, err := thing.Query(batch)
-		if err != nil {
-			return nil, fmt.Errorf("error fetching game details: %v", err)
-		}
-
-		allItems = append(allItems, gameDetails.Items...)
+	allItems, err := fetchThings(ctx, gameIDs)
+	if err != nil {
+		return nil, err
 	}
 
 	gameDetails := &thing.Items{Items: allItems}
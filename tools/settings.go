@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"os"
+	"sync"
+)
+
+// Settings holds the configurable defaults and limits used by the tools and REST routes.
+type Settings struct {
+	// Username is the BGG username used for "SELF"; BGG_USERNAME is used when empty.
+	Username string
+	// Currency and Destination are the default price currency and shipping country.
+	Currency    string
+	Destination string
+	// SearchLimit is the default number of search results.
+	SearchLimit int
+	// RulesMaxPages caps the pages of 50 threads bgg-rules reads from a rules forum.
+	RulesMaxPages int
+	// ForumSearchMaxPages is the default number of pages scanned per forum by bgg-forum-search
+	// and bgg-rules-answer.
+	ForumSearchMaxPages int
+	// RecommendationMinVotes is the default minimum votes for a recommendation.
+	RecommendationMinVotes int
+	// LudographyLimit is the default number of games listed for a designer or publisher.
+	LudographyLimit int
+}
+
+// DefaultSettings returns the built-in settings.
+func DefaultSettings() Settings {
+	return Settings{
+		Currency:               "USD",
+		Destination:            "US",
+		SearchLimit:            30,
+		RulesMaxPages:          10,
+		ForumSearchMaxPages:    3,
+		RecommendationMinVotes: 30,
+		LudographyLimit:        100,
+	}
+}
+
+var (
+	settingsMu sync.RWMutex
+	settings   = DefaultSettings()
+)
+
+// ConfigureSettings replaces the tool settings.
+func ConfigureSettings(s Settings) {
+	settingsMu.Lock()
+	settings = s
+	settingsMu.Unlock()
+}
+
+// CurrentSettings returns the tool settings.
+func CurrentSettings() Settings {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return settings
+}
+
+// defaultUsername returns the configured username used in place of "SELF".
+func defaultUsername() string {
+	if username := CurrentSettings().Username; username != "" {
+		return username
+	}
+	return os.Getenv("BGG_USERNAME")
+}
@@ -0,0 +1,166 @@
+package tools
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var searchStopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "but": true,
+	"by": true, "can": true, "do": true, "does": true, "for": true, "from": true, "how": true, "i": true,
+	"if": true, "in": true, "is": true, "it": true, "its": true, "me": true, "my": true, "of": true,
+	"on": true, "or": true, "so": true, "that": true, "the": true, "their": true, "then": true,
+	"there": true, "this": true, "to": true, "was": true, "what": true, "when": true, "where": true,
+	"which": true, "who": true, "why": true, "will": true, "with": true, "you": true, "your": true,
+}
+
+// tokenize lower-cases text and splits it into search terms, dropping stopwords and
+// single characters. A trailing plural "s" is removed so "cards" matches "card".
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if len(f) < 2 || searchStopwords[f] {
+			continue
+		}
+		if len(f) > 3 && strings.HasSuffix(f, "s") && !strings.HasSuffix(f, "ss") {
+			f = f[:len(f)-1]
+		}
+		tokens = append(tokens, f)
+	}
+	return tokens
+}
+
+// bm25Index is a small in-memory Okapi BM25 index over tokenized documents.
+type bm25Index struct {
+	docs    []map[string]int
+	lengths []int
+	docFreq map[string]int
+	avgLen  float64
+	k1, b   float64
+}
+
+func newBM25Index(documents [][]string) *bm25Index {
+	idx := &bm25Index{
+		docs:    make([]map[string]int, len(documents)),
+		lengths: make([]int, len(documents)),
+		docFreq: map[string]int{},
+		k1:      1.2,
+		b:       0.75,
+	}
+	total := 0
+	for i, tokens := range documents {
+		tf := map[string]int{}
+		for _, t := range tokens {
+			tf[t]++
+		}
+		for t := range tf {
+			idx.docFreq[t]++
+		}
+		idx.docs[i] = tf
+		idx.lengths[i] = len(tokens)
+		total += len(tokens)
+	}
+	if len(documents) > 0 {
+		idx.avgLen = float64(total) / float64(len(documents))
+	}
+	return idx
+}
+
+// Score returns the BM25 score of document i for the given query terms.
+func (idx *bm25Index) Score(i int, query []string) float64 {
+	if idx.avgLen == 0 {
+		return 0
+	}
+	n := float64(len(idx.docs))
+	score := 0.0
+	seen := map[string]bool{}
+	for _, term := range query {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		tf := float64(idx.docs[i][term])
+		if tf == 0 {
+			continue
+		}
+		df := float64(idx.docFreq[term])
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		norm := tf * (idx.k1 + 1) / (tf + idx.k1*(1-idx.b+idx.b*float64(idx.lengths[i])/idx.avgLen))
+		score += idf * norm
+	}
+	return score
+}
+
+// Rank returns document indexes with a positive score, best first.
+func (idx *bm25Index) Rank(query []string) []scoredDoc {
+	var ranked []scoredDoc
+	for i := range idx.docs {
+		if s := idx.Score(i, query); s > 0 {
+			ranked = append(ranked, scoredDoc{Index: i, Score: s})
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+	return ranked
+}
+
+type scoredDoc struct {
+	Index int
+	Score float64
+}
+
+// snippetAround returns a word-safe excerpt of text of roughly max characters centred on the
+// first occurrence of any query term, or the start of the text when no term occurs.
+func snippetAround(text string, query []string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) <= max {
+		return text
+	}
+
+	start := -1
+	for _, term := range query {
+		if i := indexFold(text, term); i >= 0 && (start == -1 || i < start) {
+			start = i
+		}
+	}
+	if start <= max/3 {
+		return truncateWordSafe(text, max)
+	}
+
+	from := start - max/3
+	for from > 0 && !utf8.RuneStart(text[from]) {
+		from--
+	}
+	if i := strings.LastIndex(text[:from], " "); i > 0 {
+		from = i + 1
+	}
+	return "…" + truncateWordSafe(text[from:], max)
+}
+
+// indexFold returns the byte offset in s of the first case-insensitive occurrence of substr,
+// or -1. Unlike searching strings.ToLower(s), the offset is always valid in s itself: lower-casing
+// changes the byte length of some letters, such as İ.
+func indexFold(s, substr string) int {
+	for i := range s {
+		if hasPrefixFold(s[i:], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	for _, want := range prefix {
+		r, size := utf8.DecodeRuneInString(s)
+		if size == 0 || unicode.ToLower(r) != unicode.ToLower(want) {
+			return false
+		}
+		s = s[size:]
+	}
+	return true
+}
@@ -0,0 +1,16 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSnippetAroundKeepsOffsetsWhenLowerCasingChangesLength(t *testing.T) {
+	// Each İ is two bytes but lower-cases to three, so offsets in the lowered text run ahead.
+	text := strings.Repeat("İİİİ ", 60) + "the Setup rule " + strings.Repeat("word ", 60)
+	got := snippetAround(text, []string{"setup"}, 80)
+	if !utf8.ValidString(got) || !strings.Contains(got, "Setup") {
+		t.Errorf("snippet = %q, want valid text around Setup", got)
+	}
+}
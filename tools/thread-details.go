@@ -6,11 +6,29 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/kkjdanie/bgg-mcp/tracing"
+	"github.com/kkjdaniel/gogeek/thing"
 	"github.com/kkjdaniel/gogeek/thread"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
+// ThreadDetailsResult keeps the fields of thread.ThreadDetail, so existing clients read it as
+// before, and adds the official post flags.
+type ThreadDetailsResult struct {
+	thread.ThreadDetail
+	Articles      []ThreadArticle
+	OfficialPosts int `json:"official_posts"`
+	// OfficialNote says why no post could be flagged official, when no account is known.
+	OfficialNote string `json:"official_note,omitempty"`
+}
+
+type ThreadArticle struct {
+	thread.Article
+	Official     bool   `json:"official"`
+	OfficialRole string `json:"official_role,omitempty"`
+}
+
 func ThreadDetailsTool() (mcp.Tool, server.ToolHandlerFunc) {
 	tool := mcp.NewTool("bgg-thread-details",
 		mcp.WithDescription("Get full content of a specific BoardGameGeek forum thread, including all posts and replies. Use this after finding relevant threads with bgg-rules."),
@@ -18,6 +36,12 @@ func ThreadDetailsTool() (mcp.Tool, server.ToolHandlerFunc) {
 			mcp.Required(),
 			mcp.Description("The BoardGameGeek thread ID to fetch"),
 		),
+		mcp.WithNumber("game_id",
+			mcp.Description("The BoardGameGeek ID of the game the thread belongs to. When provided, posters whose username spells one of the game's credited designers or publishers are flagged official, and configured official accounts tied to another game's designer or publisher are not."),
+		),
+		mcp.WithBoolean("official_only",
+			mcp.Description("Only return posts flagged as official rulings by the designer or publisher (default: false)"),
+		),
 	)
 
 	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -31,26 +55,66 @@ func ThreadDetailsTool() (mcp.Tool, server.ToolHandlerFunc) {
 			case string:
 				threadID, err = strconv.Atoi(v)
 				if err != nil {
-					return mcp.NewToolResultText("Invalid thread ID format"), nil
+					return mcp.NewToolResultError("Invalid thread ID format"), nil
 				}
 			case int:
 				threadID = v
 			}
 		} else {
-			return mcp.NewToolResultText("thread_id parameter is required"), nil
+			return mcp.NewToolResultError("thread_id parameter is required"), nil
 		}
+		done := traceBGG(ctx, "thread.Query", tracing.Int("bgg.thread_id", threadID))
 		threadDetail, err := thread.Query(threadID)
+		done(err)
 		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("Failed to get thread details: %v", err)), nil
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get thread details: %v", err)), nil
+		}
+
+		var game *thing.Item
+		if gameID, ok := arguments["game_id"].(float64); ok && gameID > 0 {
+			done := traceBGG(ctx, "thing.Query", tracing.Int("bgg.ids", 1))
+			things, err := thing.Query([]int{int(gameID)})
+			done(err)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to get game details: %v", err)), nil
+			}
+			if len(things.Items) > 0 {
+				game = &things.Items[0]
+			}
 		}
-		
-		jsonResult, err := json.MarshalIndent(threadDetail, "", "  ")
+		officialOnly, _ := arguments["official_only"].(bool)
+
+		result := flagOfficialPosts(threadDetail, newOfficialAuthors(game), officialOnly)
+
+		jsonResult, err := json.MarshalIndent(result, "", "  ")
 		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("Failed to format result: %v", err)), nil
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to format result: %v", err)), nil
 		}
 
 		return mcp.NewToolResultText(string(jsonResult)), nil
 	}
 
 	return tool, handler
-}
\ No newline at end of file
+}
+
+// flagOfficialPosts marks the articles written by the game's designer or publisher accounts,
+// optionally dropping all other articles.
+func flagOfficialPosts(td *thread.ThreadDetail, official *officialAuthors, officialOnly bool) ThreadDetailsResult {
+	result := ThreadDetailsResult{ThreadDetail: *td, Articles: []ThreadArticle{}, OfficialNote: official.Note()}
+
+	for _, article := range td.Articles {
+		role := official.Role(article.Username)
+		if role != "" {
+			result.OfficialPosts++
+		} else if officialOnly {
+			continue
+		}
+		result.Articles = append(result.Articles, ThreadArticle{
+			Article:      article,
+			Official:     role != "",
+			OfficialRole: role,
+		})
+	}
+
+	return result
+}
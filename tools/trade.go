@@ -2,10 +2,9 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"os"
 
+	"github.com/kkjdanie/bgg-mcp/tracing"
 	"github.com/kkjdaniel/gogeek/collection"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -52,6 +51,7 @@ func TradeFinderTool() (mcp.Tool, server.ToolHandlerFunc) {
 			mcp.Required(),
 			mcp.Description("BGG username whose wishlist will be checked against user1's collection"),
 		),
+		withFormatArgument(FormatJSON, FormatCSV, FormatMarkdown),
 	)
 
 	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -59,48 +59,43 @@ func TradeFinderTool() (mcp.Tool, server.ToolHandlerFunc) {
 
 		user1, ok := arguments["user1"].(string)
 		if !ok || user1 == "" {
-			return mcp.NewToolResultText("user1 is required"), nil
+			return mcp.NewToolResultError("user1 is required"), nil
 		}
 
-		if user1 == "SELF" {
-			envUsername := os.Getenv("BGG_USERNAME")
-			if envUsername == "" {
-				return mcp.NewToolResultText("BGG_USERNAME environment variable not set. Either set it or provide your specific username instead of 'SELF'."), nil
-			}
-			user1 = envUsername
+		user1, err := resolveUsername(ctx, user1)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
 		}
 
 		user2, ok := arguments["user2"].(string)
 		if !ok || user2 == "" {
-			return mcp.NewToolResultText("user2 is required"), nil
+			return mcp.NewToolResultError("user2 is required"), nil
 		}
 
-		if user2 == "SELF" {
-			envUsername := os.Getenv("BGG_USERNAME")
-			if envUsername == "" {
-				return mcp.NewToolResultText("BGG_USERNAME environment variable not set. Either set it or provide your specific username instead of 'SELF'."), nil
-			}
-			user2 = envUsername
+		user2, err = resolveUsername(ctx, user2)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
 		}
 
+		done := traceBGG(ctx, "collection.Query", tracing.Username("bgg.username", user1), tracing.Bool("bgg.owned", true))
 		user1Collection, err := collection.Query(user1, collection.WithOwned(true))
+		done(err)
 		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("Error fetching %s's collection: %v", user1, err)), nil
+			return mcp.NewToolResultError(fmt.Sprintf("Error fetching %s's collection: %v", user1, err)), nil
 		}
 
+		done = traceBGG(ctx, "collection.Query", tracing.Username("bgg.username", user2), tracing.Bool("bgg.wishlist", true))
 		user2Wishlist, err := collection.Query(user2, collection.WithWishlist(true))
+		done(err)
 		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("Error fetching %s's wishlist: %v", user2, err)), nil
+			return mcp.NewToolResultError(fmt.Sprintf("Error fetching %s's wishlist: %v", user2, err)), nil
 		}
 
 		tradeAnalysis := analyseTradeOpportunities(user1, user2, user1Collection, user2Wishlist)
 
-		out, err := json.Marshal(tradeAnalysis)
-		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("Error formatting results: %v", err)), nil
-		}
-
-		return mcp.NewToolResultText(string(out)), nil
+		return formatToolResult(formatArgument(arguments), tradeAnalysis, tableFor(func() table {
+			return tradeTable(tradeAnalysis)
+		}))
 	}
 
 	return tool, handler
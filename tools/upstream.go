@@ -0,0 +1,383 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kkjdanie/bgg-mcp/metrics"
+	"github.com/kkjdanie/bgg-mcp/tracing"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+var (
+	// ErrBGGTokenMissing is returned for BGG XML API requests refused because no application
+	// token is configured.
+	ErrBGGTokenMissing = errors.New("BGG XML API requires an application token: register an application on BoardGameGeek and set BGG_API_TOKEN (or -bgg-token / -bgg-token-file)")
+	// ErrBGGTokenRejected is returned when BGG refuses the configured application token.
+	ErrBGGTokenRejected = errors.New("BGG XML API rejected the configured application token: check that BGG_API_TOKEN is correct and the application is still approved")
+)
+
+// UpstreamConfig configures how the server talks to BoardGameGeek and the other upstream APIs.
+type UpstreamConfig struct {
+	// BGGToken is the registered application bearer token sent to the BGG XML API.
+	BGGToken string
+	// Timeout bounds each upstream request that has no deadline of its own; zero means no limit.
+	Timeout time.Duration
+	// CacheTTL is how long successful BGG XML API reads are reused; zero disables the cache.
+	CacheTTL time.Duration
+	// CacheMaxEntries bounds the number of cached responses.
+	CacheMaxEntries int
+}
+
+// BGGAuthStatus reports the outcome of the most recent BGG XML API request.
+type BGGAuthStatus struct {
+	TokenConfigured bool      `json:"token_configured"`
+	Status          string    `json:"status"`
+	LastStatusCode  int       `json:"last_status_code,omitempty"`
+	LastChecked     time.Time `json:"last_checked,omitempty"`
+}
+
+var (
+	upstreamMu    sync.RWMutex
+	upstream      UpstreamConfig
+	upstreamCache *responseCache
+	authStatus    = BGGAuthStatus{Status: "unknown"}
+	installed     sync.Once
+)
+
+// ConfigureUpstream sets the upstream configuration and installs the upstream transport as
+// http.DefaultTransport, so gogeek and the direct http calls in this package all send the
+// BGG application token, share the response cache and are recorded in metrics.
+func ConfigureUpstream(cfg UpstreamConfig) {
+	upstreamMu.Lock()
+	upstream = cfg
+	upstreamCache = nil
+	if cfg.CacheTTL > 0 {
+		upstreamCache = newResponseCache(cfg.CacheTTL, cfg.CacheMaxEntries)
+	}
+	authStatus.TokenConfigured = cfg.BGGToken != ""
+	upstreamMu.Unlock()
+
+	installed.Do(func() {
+		http.DefaultTransport = &upstreamTransport{base: http.DefaultTransport}
+	})
+}
+
+// CurrentBGGAuthStatus returns the BGG token state for health reporting.
+func CurrentBGGAuthStatus() BGGAuthStatus {
+	upstreamMu.RLock()
+	defer upstreamMu.RUnlock()
+	return authStatus
+}
+
+// upstreamTransport adds the BGG application token to XML API requests, turns
+// authentication failures into ErrBGGTokenMissing or ErrBGGTokenRejected, serves repeated
+// XML API reads from the response cache and records upstream metrics.
+type upstreamTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip records a client span for requests made within a traced tool call or REST request.
+// gogeek requests carry no context, so those calls are traced by traceBGG instead.
+func (t *upstreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if tracing.SpanFromContext(req.Context()) == nil {
+		return t.roundTrip(req, nil)
+	}
+
+	_, span := tracing.Start(req.Context(), req.Method+" "+req.URL.Hostname(), tracing.KindClient,
+		tracing.String("http.request.method", req.Method),
+		tracing.String("server.address", req.URL.Hostname()),
+		tracing.String("url.path", req.URL.Path),
+	)
+	resp, err := t.roundTrip(req, span)
+	if err != nil {
+		span.RecordError(err)
+	} else {
+		span.SetAttributes(tracing.Int("http.response.status_code", resp.StatusCode))
+		if resp.StatusCode >= 400 {
+			span.SetStatus(tracing.StatusError, resp.Status)
+		}
+	}
+	span.End()
+	return resp, err
+}
+
+func (t *upstreamTransport) roundTrip(req *http.Request, span *tracing.Span) (*http.Response, error) {
+	upstreamMu.RLock()
+	token := upstream.BGGToken
+	timeout := upstream.Timeout
+	cache := upstreamCache
+	upstreamMu.RUnlock()
+
+	bggAPI := isBGGXMLAPI(req)
+
+	cacheKey := ""
+	if cache != nil && cacheable(req, bggAPI) {
+		cacheKey = req.URL.String()
+		if entry, ok := cache.get(cacheKey); ok {
+			metrics.CacheLookups.Inc("upstream", "hit")
+			span.SetAttributes(tracing.Bool("bgg.cache_hit", true))
+			return entry.response(req), nil
+		}
+		metrics.CacheLookups.Inc("upstream", "miss")
+		span.SetAttributes(tracing.Bool("bgg.cache_hit", false))
+	}
+
+	if bggAPI && token != "" && req.Header.Get("Authorization") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	cancel := context.CancelFunc(func() {})
+	if _, hasDeadline := req.Context().Deadline(); timeout > 0 && !hasDeadline {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(req.Context(), timeout)
+		req = req.WithContext(ctx)
+	}
+
+	host := req.URL.Hostname()
+	start := time.Now()
+	metrics.InFlight.Add(1, "upstream")
+	resp, err := t.base.RoundTrip(req)
+	metrics.InFlight.Add(-1, "upstream")
+	metrics.UpstreamDuration.Observe(time.Since(start).Seconds(), host)
+	if err != nil {
+		cancel()
+		metrics.UpstreamRequests.Inc(host, "error")
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	metrics.UpstreamRequests.Inc(host, strconv.Itoa(resp.StatusCode))
+
+	if !bggAPI {
+		return cacheResponse(cache, cacheKey, resp)
+	}
+
+	status := "ok"
+	var authErr error
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		if token == "" {
+			status, authErr = "token_missing", ErrBGGTokenMissing
+		} else {
+			status, authErr = "token_rejected", ErrBGGTokenRejected
+		}
+	}
+
+	upstreamMu.Lock()
+	authStatus.Status = status
+	authStatus.LastStatusCode = resp.StatusCode
+	authStatus.LastChecked = time.Now().UTC()
+	upstreamMu.Unlock()
+
+	if authErr != nil {
+		resp.Body.Close()
+		recordAuthFailure(req.Context(), authErr)
+		return nil, fmt.Errorf("%w (HTTP %d)", authErr, resp.StatusCode)
+	}
+	return cacheResponse(cache, cacheKey, resp)
+}
+
+// cacheable reports whether req may be served from the response cache. Only anonymous BGG XML
+// API reads are cached: prices, exchange rates, geekdo JSON and anything sent with the
+// caller's own session cookie must always be fetched fresh.
+func cacheable(req *http.Request, bggAPI bool) bool {
+	return bggAPI &&
+		req.Method == http.MethodGet &&
+		req.Header.Get("Cache-Control") != "no-cache" &&
+		req.Header.Get("Cookie") == "" &&
+		req.Header.Get("Authorization") == ""
+}
+
+// cacheResponse stores successful responses when cacheKey is set. BGG answers queued
+// collection requests with 202, so only 200 responses are cached.
+func cacheResponse(cache *responseCache, cacheKey string, resp *http.Response) (*http.Response, error) {
+	if cacheKey == "" || resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	entry := &cachedResponse{key: cacheKey, statusCode: resp.StatusCode, header: resp.Header.Clone(), body: body}
+	cache.put(entry)
+	return entry.response(resp.Request), nil
+}
+
+func (e *cachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.statusCode, http.StatusText(e.statusCode)),
+		StatusCode:    e.statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header(e.header).Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+// cancelOnClose releases the request timeout once the response body has been read.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+func isBGGXMLAPI(req *http.Request) bool {
+	host := strings.TrimPrefix(strings.ToLower(req.URL.Hostname()), "www.")
+	return host == "boardgamegeek.com" && strings.HasPrefix(req.URL.Path, "/xmlapi")
+}
+
+// invalidateUpstreamCache drops cached BGG XML API responses about username, so reads after a
+// write to their account see the change. An empty username clears the whole cache.
+func invalidateUpstreamCache(username string) {
+	upstreamMu.RLock()
+	cache := upstreamCache
+	upstreamMu.RUnlock()
+	if cache == nil {
+		return
+	}
+	if username == "" {
+		cache.clear()
+		return
+	}
+	cache.removeIf(func(key string) bool {
+		u, err := url.Parse(key)
+		return err == nil && strings.EqualFold(u.Query().Get("username"), username)
+	})
+}
+
+// bggAuthError returns ErrBGGTokenMissing or ErrBGGTokenRejected when err was caused by a BGG
+// authentication failure, or nil otherwise. Errors from gogeek may not wrap the transport
+// error, so the message is matched as well.
+func bggAuthError(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, authErr := range []error{ErrBGGTokenMissing, ErrBGGTokenRejected} {
+		if errors.Is(err, authErr) || strings.Contains(err.Error(), authErr.Error()) {
+			return authErr
+		}
+	}
+	return nil
+}
+
+type authFailuresKey struct{}
+
+// authFailures holds the first BGG authentication failure seen while serving one tool call or
+// REST request, including failures the handler recovered from or reported generically.
+type authFailures struct {
+	mu  sync.Mutex
+	err error
+}
+
+func recordAuthFailure(ctx context.Context, err error) {
+	authErr := bggAuthError(err)
+	if authErr == nil {
+		return
+	}
+	if f, ok := ctx.Value(authFailuresKey{}).(*authFailures); ok {
+		f.mu.Lock()
+		if f.err == nil {
+			f.err = authErr
+		}
+		f.mu.Unlock()
+	}
+}
+
+func (f *authFailures) get() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+// BGGAuthToolMiddleware reports a missing or rejected BGG token as the tool result whenever
+// an upstream request made by the call failed authentication, instead of the generic fetch
+// error or partial result the tool would otherwise return.
+func BGGAuthToolMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		failures := &authFailures{}
+		result, err := next(context.WithValue(ctx, authFailuresKey{}, failures), request)
+
+		authErr := failures.get()
+		if authErr == nil {
+			authErr = bggAuthError(err)
+		}
+		if authErr == nil && result != nil {
+			for _, content := range result.Content {
+				if text, ok := mcp.AsTextContent(content); ok {
+					if authErr = bggAuthError(errors.New(text.Text)); authErr != nil {
+						break
+					}
+				}
+			}
+		}
+		if authErr != nil {
+			return mcp.NewToolResultError(authErr.Error()), nil
+		}
+		return result, err
+	}
+}
+
+// bggAuthErrors answers a REST request with 502 and the BGG token error when any upstream
+// request it made failed authentication. The response is buffered so the handler's own
+// output can be replaced.
+func bggAuthErrors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failures := &authFailures{}
+		buf := &bufferedResponse{header: http.Header{}, status: http.StatusOK}
+		next.ServeHTTP(buf, r.WithContext(context.WithValue(r.Context(), authFailuresKey{}, failures)))
+
+		authErr := failures.get()
+		if authErr == nil {
+			authErr = bggAuthError(errors.New(buf.body.String()))
+		}
+		if authErr != nil {
+			writeJSONStatus(w, http.StatusBadGateway, map[string]string{"error": authErr.Error()})
+			return
+		}
+		for k, v := range buf.header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(buf.status)
+		_, _ = w.Write(buf.body.Bytes())
+	})
+}
+
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header         { return b.header }
+func (b *bufferedResponse) WriteHeader(status int)      { b.status = status }
+func (b *bufferedResponse) Write(p []byte) (int, error) { return b.body.Write(p) }
+
+// traceBGG starts a client span around a gogeek call, which takes no context and so cannot be
+// traced by the transport, and returns a func that ends the span with the call's error.
+func traceBGG(ctx context.Context, operation string, attrs ...tracing.Attribute) func(error) {
+	_, span := tracing.Start(ctx, "bgg "+operation, tracing.KindClient, attrs...)
+	return func(err error) {
+		recordAuthFailure(ctx, err)
+		span.RecordError(err)
+		span.End()
+	}
+}
@@ -0,0 +1,176 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// standInUpstream starts a server that answers for every host, and returns a client whose
+// requests go through upstreamTransport to it. handler sees the original Host header.
+func standInUpstream(t *testing.T, token string, handler http.HandlerFunc) *http.Client {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	base := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+		},
+	}
+	t.Cleanup(base.CloseIdleConnections)
+
+	upstreamMu.Lock()
+	saved, savedCache, savedStatus := upstream, upstreamCache, authStatus
+	upstream, upstreamCache = UpstreamConfig{BGGToken: token}, nil
+	upstreamMu.Unlock()
+	t.Cleanup(func() {
+		upstreamMu.Lock()
+		upstream, upstreamCache, authStatus = saved, savedCache, savedStatus
+		upstreamMu.Unlock()
+	})
+
+	return &http.Client{Transport: &upstreamTransport{base: base}}
+}
+
+func TestUpstreamSendsTokenToBGGXMLAPIOnly(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]string{}
+	client := standInUpstream(t, "secret", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.Host+r.URL.Path] = r.Header.Get("Authorization")
+		mu.Unlock()
+		_, _ = w.Write([]byte("<items/>"))
+	})
+
+	want := map[string]string{
+		"http://boardgamegeek.com/xmlapi2/thing?id=13":     "Bearer secret",
+		"http://www.boardgamegeek.com/xmlapi2/user?name=x": "Bearer secret",
+		"http://boardgamegeek.com/geekplay.php":            "",
+		"http://api.geekdo.com/api/geekmarket/products":    "",
+		"http://boardgameprices.co.uk/api/info?eid=13":     "",
+		"http://example.com/xmlapi2/thing?id=13":           "",
+		"http://boardgamegeek.com.example.com/xmlapi2/hot": "",
+	}
+	for rawURL, auth := range want {
+		resp, err := client.Get(rawURL)
+		if err != nil {
+			t.Fatalf("GET %s: %v", rawURL, err)
+		}
+		resp.Body.Close()
+
+		req, _ := http.NewRequest(http.MethodGet, rawURL, nil)
+		if got := seen[req.URL.Host+req.URL.Path]; got != auth {
+			t.Errorf("GET %s sent Authorization %q, want %q", rawURL, got, auth)
+		}
+	}
+}
+
+func TestUpstreamReportsRejectedToken(t *testing.T) {
+	client := standInUpstream(t, "stale", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	_, err := client.Get("http://boardgamegeek.com/xmlapi2/thing?id=13")
+	if !errors.Is(err, ErrBGGTokenRejected) {
+		t.Fatalf("err = %v, want ErrBGGTokenRejected", err)
+	}
+	if status := CurrentBGGAuthStatus(); status.Status != "token_rejected" || status.LastStatusCode != http.StatusUnauthorized {
+		t.Errorf("auth status = %+v, want token_rejected with 401", status)
+	}
+}
+
+func TestBGGAuthToolMiddlewareReportsSwallowedFailure(t *testing.T) {
+	client := standInUpstream(t, "", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	saved := http.DefaultClient
+	http.DefaultClient = client
+	t.Cleanup(func() { http.DefaultClient = saved })
+
+	// The tool hides the upstream error behind a generic message.
+	handler := BGGAuthToolMiddleware(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if _, err := httpGet(ctx, "http://boardgamegeek.com/xmlapi2/thing?id=13"); err != nil {
+			return mcp.NewToolResultText("No games found"), nil
+		}
+		return mcp.NewToolResultText("ok"), nil
+	})
+
+	result, err := handler(context.Background(), mcp.CallToolRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := mcp.AsTextContent(result.Content[0])
+	if !result.IsError || text.Text != ErrBGGTokenMissing.Error() {
+		t.Errorf("result = %q (error %t), want the missing token error", text.Text, result.IsError)
+	}
+}
+
+func TestBGGAuthErrorsAnswersRESTWith502(t *testing.T) {
+	client := standInUpstream(t, "stale", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	saved := http.DefaultClient
+	http.DefaultClient = client
+	t.Cleanup(func() { http.DefaultClient = saved })
+
+	handler := bggAuthErrors(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v struct{}
+		if err := fetchXML(r.Context(), "http://boardgamegeek.com/xmlapi2/hot", &v); err != nil {
+			writeJSON(w, map[string]string{"error": "fetch failed"})
+		}
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/bgg/hot", nil))
+	if rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), "rejected") {
+		t.Errorf("got %d %s, want 502 with the rejected token error", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+}
+
+func TestUpstreamCachesBGGXMLAPIReadsOnly(t *testing.T) {
+	var mu sync.Mutex
+	hits := map[string]int{}
+	client := standInUpstream(t, "", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.Host+r.URL.Path]++
+		mu.Unlock()
+		_, _ = w.Write([]byte("<items/>"))
+	})
+	upstreamMu.Lock()
+	upstreamCache = newResponseCache(time.Minute, 10)
+	upstreamMu.Unlock()
+
+	want := map[string]int{
+		"http://boardgamegeek.com/xmlapi2/thing?id=13":  1,
+		"http://api.geekdo.com/api/geekmarket/products": 2,
+		"http://example.com/rates.json":                 2,
+	}
+	for rawURL := range want {
+		for i := 0; i < 2; i++ {
+			resp, err := client.Get(rawURL)
+			if err != nil {
+				t.Fatalf("GET %s: %v", rawURL, err)
+			}
+			resp.Body.Close()
+		}
+	}
+	for rawURL, n := range want {
+		req, _ := http.NewRequest(http.MethodGet, rawURL, nil)
+		if got := hits[req.URL.Host+req.URL.Path]; got != n {
+			t.Errorf("%s reached upstream %d times, want %d", rawURL, got, n)
+		}
+	}
+}
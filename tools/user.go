@@ -3,8 +3,8 @@ package tools
 import (
 	"context"
 	"encoding/json"
-	"os"
 
+	"github.com/kkjdanie/bgg-mcp/tracing"
 	"github.com/kkjdaniel/gogeek/user"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -23,17 +23,16 @@ func UserTool() (mcp.Tool, server.ToolHandlerFunc) {
 		arguments := request.GetArguments()
 		name := arguments["username"].(string)
 
-		if name == "SELF" {
-			envUsername := os.Getenv("BGG_USERNAME")
-			if envUsername == "" {
-				return mcp.NewToolResultText("BGG_USERNAME environment variable not set. Either set it or provide your specific username instead of 'SELF'."), nil
-			}
-			name = envUsername
+		name, err := resolveUsername(ctx, name)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
 		}
 
+		done := traceBGG(ctx, "user.Query", tracing.Username("bgg.username", name))
 		userDetails, err := user.Query(name)
+		done(err)
 		if err != nil {
-			return mcp.NewToolResultText(err.Error()), nil
+			return mcp.NewToolResultError(err.Error()), nil
 		}
 
 		out, _ := json.Marshal(userDetails)
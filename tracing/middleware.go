@@ -0,0 +1,198 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// usernameArguments are tool arguments holding BGG usernames, redacted when configured. They
+// are also redacted inside object and array arguments, such as bgg-log-play's players.
+var usernameArguments = map[string]bool{
+	"username": true,
+	"user":     true,
+	"user1":    true,
+	"user2":    true,
+}
+
+// maxAttributeLength bounds string attribute values so long questions or queries do not bloat
+// spans.
+const maxAttributeLength = 256
+
+// ToolMiddleware starts a span for every MCP tool call with the tool name and its arguments as
+// attributes. Spans started by the handler, including upstream BGG calls, become its children.
+func ToolMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if !Enabled() {
+			return next(ctx, request)
+		}
+
+		tool := request.Params.Name
+		attrs := []Attribute{
+			String("mcp.method.name", "tools/call"),
+			String("gen_ai.tool.name", tool),
+		}
+		if session := server.ClientSessionFromContext(ctx); session != nil && session.SessionID() != "" {
+			attrs = append(attrs, String("mcp.session.id", session.SessionID()))
+		}
+		attrs = append(attrs, argumentAttributes(request.GetArguments())...)
+
+		ctx, span := Start(ctx, "tools/call "+tool, KindServer, attrs...)
+		defer span.End()
+
+		result, err := next(ctx, request)
+		if err != nil {
+			span.RecordError(err)
+		} else if result != nil && result.IsError {
+			span.SetStatus(StatusError, "tool returned an error result")
+		}
+		return result, err
+	}
+}
+
+// argumentAttributes converts tool arguments to mcp.tool.argument.<name> attributes in a stable
+// order.
+func argumentAttributes(args map[string]any) []Attribute {
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]Attribute, 0, len(keys))
+	for _, k := range keys {
+		key := "mcp.tool.argument." + k
+		switch v := args[k].(type) {
+		case string:
+			if usernameArguments[k] {
+				attrs = append(attrs, Username(key, v))
+			} else {
+				attrs = append(attrs, String(key, truncate(v)))
+			}
+		case float64:
+			attrs = append(attrs, Float64(key, v))
+		case bool:
+			attrs = append(attrs, Bool(key, v))
+		case nil:
+		default:
+			encoded, err := json.Marshal(redactNested(v))
+			if err != nil {
+				encoded = []byte(fmt.Sprint(v))
+			}
+			attrs = append(attrs, String(key, truncate(string(encoded))))
+		}
+	}
+	return attrs
+}
+
+// redactNested returns a copy of an object or array argument with the values of username keys
+// redacted at any depth.
+func redactNested(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			if s, ok := item.(string); ok && usernameArguments[k] {
+				out[k] = redactUsername(s)
+			} else {
+				out[k] = redactNested(item)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = redactNested(item)
+		}
+		return out
+	}
+	return v
+}
+
+// truncate shortens s to at most maxAttributeLength bytes without splitting a UTF-8 character.
+func truncate(s string) string {
+	if len(s) <= maxAttributeLength {
+		return s
+	}
+	cut := maxAttributeLength
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
+
+// Handler starts a server span for each request to next, continuing the trace from an incoming
+// traceparent header. route names the span and is recorded as http.route.
+func Handler(route string, next http.Handler) http.Handler {
+	return handler(func(*http.Request) string { return route }, next)
+}
+
+// InstrumentHandler is Handler with the route taken from the pattern mux matches, so path
+// parameters do not end up in span names.
+func InstrumentHandler(mux *http.ServeMux, next http.Handler) http.Handler {
+	return handler(func(r *http.Request) string {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			return pattern
+		}
+		return "unmatched"
+	}, next)
+}
+
+func handler(route func(*http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		routeName := route(r)
+		ctx := Extract(r.Context(), r.Header)
+		ctx, span := Start(ctx, r.Method+" "+routeName, KindServer,
+			String("http.request.method", r.Method),
+			String("http.route", routeName),
+			String("url.path", r.URL.Path),
+			String("user_agent.original", r.UserAgent()),
+		)
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		span.SetAttributes(Int("http.response.status_code", rec.status))
+		if rec.status >= 500 {
+			span.SetStatus(StatusError, http.StatusText(rec.status))
+		}
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming responses working through the recorder.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
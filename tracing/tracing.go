@@ -0,0 +1,369 @@
+// Package tracing records OpenTelemetry-compatible traces for MCP tool calls, REST requests and
+// upstream BoardGameGeek calls, and exports them over OTLP/HTTP. It implements the small part of
+// the OpenTelemetry API the server needs so no SDK dependency is required.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SpanKind is the OTLP span kind.
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// StatusCode is the OTLP span status code.
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+type TraceID [16]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+func (id TraceID) IsValid() bool  { return id != TraceID{} }
+
+type SpanID [8]byte
+
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+func (id SpanID) IsValid() bool  { return id != SpanID{} }
+
+// SpanContext identifies a span and carries its sampling decision across process boundaries.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+	Remote  bool
+}
+
+func (sc SpanContext) IsValid() bool { return sc.TraceID.IsValid() && sc.SpanID.IsValid() }
+
+// Attribute is a span attribute. Value is a string, int64, float64 or bool.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+func String(key, value string) Attribute          { return Attribute{Key: key, Value: value} }
+func Int(key string, value int) Attribute         { return Attribute{Key: key, Value: int64(value)} }
+func Float64(key string, value float64) Attribute { return Attribute{Key: key, Value: value} }
+func Bool(key string, value bool) Attribute       { return Attribute{Key: key, Value: value} }
+
+// Username returns an attribute for a BGG username, replaced by "[redacted]" when the provider
+// is configured to redact usernames.
+func Username(key, username string) Attribute {
+	return String(key, redactUsername(username))
+}
+
+// redactUsername returns "[redacted]" in place of a non-empty username when the provider
+// redacts usernames.
+func redactUsername(username string) string {
+	if p := global.Load(); p != nil && p.cfg.RedactUsernames && username != "" {
+		return redacted
+	}
+	return username
+}
+
+const redacted = "[redacted]"
+
+// SpanData is the immutable record of an ended span handed to exporters.
+type SpanData struct {
+	Name          string
+	Kind          SpanKind
+	TraceID       TraceID
+	SpanID        SpanID
+	ParentSpanID  SpanID
+	Start         time.Time
+	End           time.Time
+	Attributes    []Attribute
+	Status        StatusCode
+	StatusMessage string
+}
+
+// Span is an in-progress span. A nil *Span is valid and records nothing, which is what Start
+// returns when tracing is disabled.
+type Span struct {
+	provider *Provider
+	sc       SpanContext
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SpanContext returns the span's identifiers, or the zero value for a nil span.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttributes adds or replaces attributes on the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil || !s.sc.Sampled {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, attr := range attrs {
+		replaced := false
+		for i := range s.data.Attributes {
+			if s.data.Attributes[i].Key == attr.Key {
+				s.data.Attributes[i] = attr
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			s.data.Attributes = append(s.data.Attributes, attr)
+		}
+	}
+}
+
+// RecordError marks the span as failed with err's message. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.SetStatus(StatusError, err.Error())
+}
+
+// SetStatus sets the span status; the message is only kept for StatusError.
+func (s *Span) SetStatus(code StatusCode, message string) {
+	if s == nil || !s.sc.Sampled {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Status = code
+	if code == StatusError {
+		s.data.StatusMessage = message
+	}
+}
+
+// End finishes the span and queues it for export. Calls after the first are ignored.
+func (s *Span) End() {
+	if s == nil || !s.sc.Sampled {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	s.provider.enqueue(data)
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// SpanFromContext returns the current span in ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemoteSpanContext returns ctx carrying a parent span received from another process.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// Start begins a span as a child of the span in ctx, or of a remote parent extracted from an
+// incoming request, or as a new root. It returns ctx carrying the new span. When no provider
+// is configured the returned span is nil and ctx is returned unchanged.
+func Start(ctx context.Context, name string, kind SpanKind, attrs ...Attribute) (context.Context, *Span) {
+	p := global.Load()
+	if p == nil {
+		return ctx, nil
+	}
+
+	var parent SpanContext
+	if span := SpanFromContext(ctx); span != nil {
+		parent = span.sc
+	} else if remote, ok := ctx.Value(remoteKey{}).(SpanContext); ok {
+		parent = remote
+	}
+
+	sc := SpanContext{SpanID: newSpanID()}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Sampled = parent.Sampled
+	} else {
+		sc.TraceID = newTraceID()
+		sc.Sampled = p.sample(sc.TraceID)
+	}
+
+	span := &Span{provider: p, sc: sc}
+	if sc.Sampled {
+		span.data = SpanData{
+			Name:         name,
+			Kind:         kind,
+			TraceID:      sc.TraceID,
+			SpanID:       sc.SpanID,
+			ParentSpanID: parent.SpanID,
+			Start:        time.Now(),
+			Attributes:   append([]Attribute(nil), attrs...),
+		}
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// Enabled reports whether a provider is installed.
+func Enabled() bool {
+	return global.Load() != nil
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// global is the installed provider; nil disables tracing.
+var global atomic.Pointer[Provider]
+
+// Provider owns the tracing configuration and the batching span processor.
+type Provider struct {
+	cfg   Config
+	queue chan SpanData
+	done  chan struct{}
+	once  sync.Once
+}
+
+// Config configures a Provider.
+type Config struct {
+	// Exporter receives batches of ended spans.
+	Exporter Exporter
+	// SampleRatio is the fraction of new traces recorded; traces continued from an incoming
+	// traceparent follow the caller's decision.
+	SampleRatio float64
+	// RedactUsernames replaces BGG usernames in span attributes with "[redacted]".
+	RedactUsernames bool
+	// BatchSize and BatchTimeout control how often spans are exported.
+	BatchSize    int
+	BatchTimeout time.Duration
+}
+
+// NewProvider starts a provider exporting to cfg.Exporter. Install it with SetProvider and stop
+// it with Shutdown to flush pending spans.
+func NewProvider(cfg Config) *Provider {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 512
+	}
+	if cfg.BatchTimeout <= 0 {
+		cfg.BatchTimeout = 5 * time.Second
+	}
+
+	p := &Provider{
+		cfg:   cfg,
+		queue: make(chan SpanData, 4*cfg.BatchSize),
+		done:  make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// SetProvider installs p as the global provider; nil disables tracing.
+func SetProvider(p *Provider) {
+	global.Store(p)
+}
+
+// Shutdown exports pending spans and shuts the exporter down.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	p.once.Do(func() { close(p.queue) })
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return p.cfg.Exporter.Shutdown(ctx)
+}
+
+func (p *Provider) sample(id TraceID) bool {
+	switch {
+	case p.cfg.SampleRatio >= 1:
+		return true
+	case p.cfg.SampleRatio <= 0:
+		return false
+	}
+	// Same rule as the OpenTelemetry TraceIdRatioBased sampler: compare the low 63 bits.
+	bound := uint64(p.cfg.SampleRatio * (1 << 63))
+	return binary.BigEndian.Uint64(id[8:])>>1 < bound
+}
+
+// enqueue hands an ended span to the batch processor, dropping it if the queue is full so a slow
+// collector never blocks tool calls.
+func (p *Provider) enqueue(data SpanData) {
+	defer func() { recover() }() // the queue is closed after Shutdown
+	select {
+	case p.queue <- data:
+	default:
+	}
+}
+
+func (p *Provider) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.cfg.BatchTimeout)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, p.cfg.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := p.cfg.Exporter.Export(ctx, batch); err != nil {
+			log.Printf("Trace export failed: %v", err)
+		}
+		cancel()
+		batch = make([]SpanData, 0, p.cfg.BatchSize)
+	}
+
+	for {
+		select {
+		case data, ok := <-p.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, data)
+			if len(batch) >= p.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
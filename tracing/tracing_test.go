@@ -0,0 +1,148 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// recordSpans installs a provider exporting to memory for the test. Call the returned function
+// to flush and read the ended spans.
+func recordSpans(t *testing.T, redact bool) func() []SpanData {
+	t.Helper()
+	exp := &InMemoryExporter{}
+	p := NewProvider(Config{Exporter: exp, SampleRatio: 1, RedactUsernames: redact})
+	SetProvider(p)
+	t.Cleanup(func() { SetProvider(nil) })
+	return func() []SpanData {
+		if err := p.Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return exp.Spans()
+	}
+}
+
+func findSpan(t *testing.T, spans []SpanData, name string) SpanData {
+	t.Helper()
+	for _, s := range spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	t.Fatalf("no span %q in %d spans", name, len(spans))
+	return SpanData{}
+}
+
+func attribute(s SpanData, key string) any {
+	for _, a := range s.Attributes {
+		if a.Key == key {
+			return a.Value
+		}
+	}
+	return nil
+}
+
+func TestToolMiddlewareRecordsArgumentsAndChildren(t *testing.T) {
+	flush := recordSpans(t, true)
+
+	handler := ToolMiddleware(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		_, child := Start(ctx, "bgg thing", KindClient)
+		child.End()
+		return mcp.NewToolResultText("ok"), nil
+	})
+	var request mcp.CallToolRequest
+	request.Params.Name = "bgg-log-play"
+	request.Params.Arguments = map[string]any{
+		"username": "alice",
+		"players":  []any{map[string]any{"name": "Al", "username": "alice"}},
+		"query":    strings.Repeat("é", maxAttributeLength),
+	}
+	if _, err := handler(context.Background(), request); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := flush()
+	tool := findSpan(t, spans, "tools/call bgg-log-play")
+	if got := attribute(tool, "mcp.tool.argument.username"); got != redacted {
+		t.Errorf("username = %v, want %q", got, redacted)
+	}
+	if players, _ := attribute(tool, "mcp.tool.argument.players").(string); strings.Contains(players, "alice") || !strings.Contains(players, "Al") {
+		t.Errorf("players = %q, want the player name with the username redacted", players)
+	}
+	if query, _ := attribute(tool, "mcp.tool.argument.query").(string); !utf8.ValidString(query) || len(query) > maxAttributeLength+len("…") {
+		t.Errorf("query is %d bytes, valid UTF-8 %t", len(query), utf8.ValidString(query))
+	}
+
+	child := findSpan(t, spans, "bgg thing")
+	if child.TraceID != tool.TraceID || child.ParentSpanID != tool.SpanID {
+		t.Errorf("child span is not a child of the tool span")
+	}
+}
+
+func TestToolMiddlewareMarksErrorResults(t *testing.T) {
+	flush := recordSpans(t, false)
+
+	tests := []struct {
+		tool   string
+		result *mcp.CallToolResult
+		want   StatusCode
+	}{
+		{"bgg-hot", mcp.NewToolResultText(`[]`), StatusUnset},
+		{"bgg-collection", mcp.NewToolResultError("Error fetching collection: timeout"), StatusError},
+	}
+	for _, tt := range tests {
+		handler := ToolMiddleware(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return tt.result, nil
+		})
+		var request mcp.CallToolRequest
+		request.Params.Name = tt.tool
+		if _, err := handler(context.Background(), request); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	spans := flush()
+	for _, tt := range tests {
+		if span := findSpan(t, spans, "tools/call "+tt.tool); span.Status != tt.want {
+			t.Errorf("%s span status = %v, want %v", tt.tool, span.Status, tt.want)
+		}
+	}
+}
+
+func TestHandlerContinuesIncomingTrace(t *testing.T) {
+	flush := recordSpans(t, false)
+
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler := Handler("/v1/bgg/hot", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/v1/bgg/hot", nil)
+	req.Header.Set("traceparent", FormatTraceparent(remote))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	span := findSpan(t, flush(), "GET /v1/bgg/hot")
+	if span.TraceID != remote.TraceID || span.ParentSpanID != remote.SpanID {
+		t.Errorf("span trace %s parent %s, want %s and %s", span.TraceID, span.ParentSpanID, remote.TraceID, remote.SpanID)
+	}
+	if span.Status != StatusError || attribute(span, "http.response.status_code") != int64(http.StatusBadGateway) {
+		t.Errorf("span status %v with attributes %v, want an error with status code 502", span.Status, span.Attributes)
+	}
+}
+
+func TestProviderFromEnvFallsBackToHTTPJSON(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4317")
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc")
+
+	p, err := ProviderFromEnv()
+	if err != nil || p == nil {
+		t.Fatalf("ProviderFromEnv() = %v, %v; want a provider", p, err)
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}